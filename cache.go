@@ -0,0 +1,83 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the translation cache",
+	Long: `Translations are kept in a cache, so the same text
+is not sent twice to the translation service.`,
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Display the content of the translation cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(printCacheStats())
+	},
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every translation from the cache",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(clearCache())
+	},
+}
+
+func printCacheStats() error {
+	store, err := cacheStore()
+	if err != nil {
+		return err
+	}
+	st, err := store.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Directory: %s\n", store.Dir)
+	fmt.Printf("Entries: %d\n", st.Entries)
+	fmt.Printf("Size: %d/%d bytes\n", st.Size, store.MaxSize)
+	if !st.Oldest.IsZero() {
+		fmt.Printf("Oldest: %s\n", st.Oldest.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func clearCache() error {
+	store, err := cacheStore()
+	if err != nil {
+		return err
+	}
+	return store.Clear()
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
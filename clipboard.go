@@ -19,7 +19,6 @@ package main
 
 import (
 	"github.com/spf13/cobra"
-	"log"
 )
 
 // clipboardCmd represents the clipboard command
@@ -30,12 +29,8 @@ var clipboardCmd = &cobra.Command{
 Works on Windows, MacOS and Linux/Unix (require xsel or xclip).`,
 	Run: func(cmd *cobra.Command, args []string) {
 		t, err := defaultClipboard.Read()
-		if err != nil {
-			log.Fatal(err)
-		}
-		if err := translate(t); err != nil {
-			log.Fatal(err)
-		}
+		exitOnError(err)
+		exitOnError(translate(t))
 	},
 }
 
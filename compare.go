@@ -0,0 +1,97 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"github.com/rangzen/t2/pkg/t2"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var compareServices []string
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use: "compare [flags] \"Text to translate.\"",
+	Example: `t2 compare --pivot FR "I will treat my wound."
+t2 compare --services deepl,mock "I will treat my wound."`,
+	Short: "Compare the double translation of every translation service",
+	Long: `Run the double translation through every translation service
+configured in the configuration file, then show the difference
+between their results.
+When the services agree on a correction, it is likely a real mistake.
+The offline services, like mock, are compared only if given with --services.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(compare(args[0]))
+	},
+}
+
+func compare(t string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	var backends []t2.Backend
+	for _, service := range servicesToCompare() {
+		b, err := selectBackend(service)
+		if err != nil {
+			return err
+		}
+		backends = append(backends, b)
+	}
+
+	c := t2.Config{
+		SourceLang: sourceLang,
+		Routes:     routes(),
+	}
+
+	svc, err := newT2(c, nil, nil)
+	if err != nil {
+		return err
+	}
+	res, err := svc.Compare(ctx, t, backends)
+	if err != nil {
+		return err
+	}
+	r, err := renderer()
+	if err != nil {
+		return err
+	}
+	return r.Comparison(os.Stdout, res)
+}
+
+// servicesToCompare returns the services given with --services,
+// or the configured services working online.
+func servicesToCompare() []string {
+	if len(compareServices) > 0 {
+		return compareServices
+	}
+	var services []string
+	for _, service := range configuredServices() {
+		if r, ok := lookupService(service); ok && !r.Offline {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringSliceVar(&compareServices, "services", nil, "comma separated translation services to compare (default is every configured online service)")
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/mask"
+	"log"
+	"os"
+)
+
+// Exit codes, one for each kind of error.
+const (
+	exitError               = 1
+	exitAuth                = 3
+	exitQuotaExceeded       = 4
+	exitRateLimited         = 5
+	exitUnsupportedLanguage = 6
+	exitNetwork             = 7
+	exitMalformedResponse   = 8
+	exitServer              = 9
+	exitRequest             = 10
+	exitTimeout             = 11
+	exitPlaceholder         = 12
+	exitUnsupported         = 13
+	exitInterrupted         = 130
+)
+
+var exitCodes = []struct {
+	kind error
+	code int
+}{
+	{backend.ErrAuth, exitAuth},
+	{backend.ErrQuotaExceeded, exitQuotaExceeded},
+	{backend.ErrRateLimited, exitRateLimited},
+	{backend.ErrUnsupportedLanguage, exitUnsupportedLanguage},
+	{backend.ErrNetwork, exitNetwork},
+	{backend.ErrMalformedResponse, exitMalformedResponse},
+	{backend.ErrServer, exitServer},
+	{backend.ErrRequest, exitRequest},
+	{backend.ErrUnsupported, exitUnsupported},
+	{mask.ErrLostPlaceholder, exitPlaceholder},
+	{mask.ErrDuplicatedPlaceholder, exitPlaceholder},
+	{mask.ErrUnknownPlaceholder, exitPlaceholder},
+	{context.DeadlineExceeded, exitTimeout},
+	{context.Canceled, exitInterrupted},
+}
+
+// exitOnError prints the error, if any, and exits with the code matching its kind.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	log.Print(err)
+	os.Exit(exitCode(err))
+}
+
+func exitCode(err error) int {
+	for _, ec := range exitCodes {
+		if errors.Is(err, ec.kind) {
+			return ec.code
+		}
+	}
+	return exitError
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"context"
+	"github.com/rangzen/t2/pkg/markdown"
+	"github.com/rangzen/t2/pkg/t2"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var batchSize int
+var markdownInput bool
+
+// fileCmd represents the file command
+var fileCmd = &cobra.Command{
+	Use:   "file [flags] FILE",
+	Short: "Use a file as input, paragraph by paragraph",
+	Long: `Use a file as input, or the standard input with "-".
+The file is split into paragraphs on blank lines and each paragraph
+is double translated. Paragraphs that come back identical are marked clean.
+With --markdown, code blocks are not translated, and inline code,
+link targets and URLs are kept as is.`,
+	Example: `t2 file README.md
+cat README.md | t2 file -
+t2 file --markdown README.md`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(translateFile(args[0]))
+	},
+}
+
+func translateFile(name string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	text, err := readInput(name)
+	if err != nil {
+		return err
+	}
+
+	forward, err := selectBackend(serviceOrDefault(forwardService))
+	if err != nil {
+		return err
+	}
+	back, err := selectBackend(serviceOrDefault(backService))
+	if err != nil {
+		return err
+	}
+
+	c := t2.Config{
+		SourceLang:      sourceLang,
+		Routes:          routes(),
+		CopyToClipboard: copyToClipboard && !markdownInput,
+		BatchSize:       batchSize,
+	}
+
+	var patterns []string
+	if markdownInput {
+		patterns = markdown.Patterns
+	}
+	svc, err := newT2(c, forward, back, patterns...)
+	if err != nil {
+		return err
+	}
+	var doc t2.Document
+	if markdownInput {
+		doc, err = translateMarkdown(ctx, svc, text)
+	} else {
+		doc, err = svc.TranslateDocument(ctx, t2.SplitParagraphs(text))
+	}
+	if err != nil {
+		return err
+	}
+	r, err := renderer()
+	if err != nil {
+		return err
+	}
+	return r.Document(os.Stdout, doc)
+}
+
+// translateMarkdown translates only the prose of the Markdown document.
+// The service must protect the markdown.Patterns.
+// If the --to-clipboard flag is set, the reassembled document is copied to the clipboard.
+func translateMarkdown(ctx context.Context, svc t2.T2, text string) (t2.Document, error) {
+	blocks := markdown.Split(text)
+	doc, err := svc.TranslateDocument(ctx, markdown.Prose(blocks))
+	if err != nil {
+		return t2.Document{}, err
+	}
+
+	if copyToClipboard {
+		finals := make([]string, len(doc.Paragraphs))
+		for i, p := range doc.Paragraphs {
+			finals[i] = p.Routes[0].Final()
+		}
+		if err := defaultClipboard.Write(markdown.Join(blocks, finals)); err != nil {
+			return t2.Document{}, err
+		}
+	}
+	return doc, nil
+}
+
+// readInput returns the content of the file, or of the standard input for "-".
+func readInput(name string) (string, error) {
+	var b []byte
+	var err error
+	if name == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	return string(b), err
+}
+
+func init() {
+	rootCmd.AddCommand(fileCmd)
+
+	fileCmd.Flags().BoolVarP(&markdownInput, "markdown", "m", false, "translate only the prose of a Markdown file")
+	fileCmd.Flags().IntVarP(&batchSize, "batch-size", "b", 50, "number of paragraphs sent in a single batch")
+}
@@ -0,0 +1,196 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/backend/deepl"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+var glossaryFormat string
+
+// glossaryCmd represents the glossary command
+var glossaryCmd = &cobra.Command{
+	Use:   "glossary",
+	Short: "Manage the DeepL glossaries",
+	Long: `Glossaries fix the translation of product names and technical terms.
+Set GlossaryID in the DeepL section to use a glossary for its language pair,
+or AutoGlossary: true to use the most recent glossary of the language pair of each translation.`,
+}
+
+// glossaryCreateCmd represents the glossary create command
+var glossaryCreateCmd = &cobra.Command{
+	Use:   "create NAME FILE",
+	Short: "Create a glossary from a TSV or CSV file, from --source to --pivot",
+	Long: `Create a glossary from a file with one "source term, target term" entry per line,
+separated by a tab (TSV) or a comma (CSV). The format is deduced from the extension of the file,
+unless --format is given.`,
+	Example: `t2 glossary create products --source EN --pivot FR products.tsv`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(createGlossary(args[0], args[1]))
+	},
+}
+
+// glossaryListCmd represents the glossary list command
+var glossaryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the glossaries",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(listGlossaries())
+	},
+}
+
+// glossaryShowCmd represents the glossary show command
+var glossaryShowCmd = &cobra.Command{
+	Use:   "show ID",
+	Short: "Display the entries of a glossary",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(showGlossary(args[0]))
+	},
+}
+
+// glossaryDeleteCmd represents the glossary delete command
+var glossaryDeleteCmd = &cobra.Command{
+	Use:   "delete ID",
+	Short: "Delete a glossary",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(deleteGlossary(args[0]))
+	},
+}
+
+// deeplService returns the DeepL translation service of the configuration file.
+func deeplService() (deepl.TranslationService, error) {
+	r, ok := backend.Lookup("deepl")
+	if !ok {
+		return deepl.TranslationService{}, errors.New("unknown translation service")
+	}
+	settings := serviceSettings(r)
+	client, err := httpClient()
+	if err != nil {
+		return deepl.TranslationService{}, err
+	}
+	settings.Client = client
+	b, err := r.Create(settings)
+	if err != nil {
+		return deepl.TranslationService{}, err
+	}
+	return b.(deepl.TranslationService), nil
+}
+
+func createGlossary(name string, path string) error {
+	if len(pivotLangs) != 1 {
+		return errors.New("a glossary has a single target language, use a single --pivot")
+	}
+	format := glossaryFormat
+	if format == "" {
+		format = "tsv"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = "csv"
+		}
+	}
+	entries, err := readInput(path)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+	d, err := deeplService()
+	if err != nil {
+		return err
+	}
+	g, err := d.CreateGlossary(ctx, name, sourceLang, pivotLangs[0], entries, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(g.GlossaryID)
+	return nil
+}
+
+func listGlossaries() error {
+	ctx, cancel := newContext()
+	defer cancel()
+	d, err := deeplService()
+	if err != nil {
+		return err
+	}
+	glossaries, err := d.Glossaries(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tLANGUAGES\tENTRIES\tREADY\tCREATED")
+	for _, g := range glossaries {
+		fmt.Fprintf(w, "%s\t%s\t%s -> %s\t%d\t%t\t%s\n", g.GlossaryID, g.Name,
+			strings.ToUpper(g.SourceLang), strings.ToUpper(g.TargetLang), g.EntryCount, g.Ready,
+			g.CreationTime.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+func showGlossary(id string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	d, err := deeplService()
+	if err != nil {
+		return err
+	}
+	g, err := d.Glossary(ctx, id)
+	if err != nil {
+		return err
+	}
+	entries, err := d.GlossaryEntries(ctx, id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# %s (%s -> %s, %d entries)\n", g.Name, strings.ToUpper(g.SourceLang), strings.ToUpper(g.TargetLang), g.EntryCount)
+	fmt.Print(strings.TrimSuffix(entries, "\n") + "\n")
+	return nil
+}
+
+func deleteGlossary(id string) error {
+	ctx, cancel := newContext()
+	defer cancel()
+	d, err := deeplService()
+	if err != nil {
+		return err
+	}
+	return d.DeleteGlossary(ctx, id)
+}
+
+func init() {
+	rootCmd.AddCommand(glossaryCmd)
+	glossaryCmd.AddCommand(glossaryCreateCmd)
+	glossaryCmd.AddCommand(glossaryListCmd)
+	glossaryCmd.AddCommand(glossaryShowCmd)
+	glossaryCmd.AddCommand(glossaryDeleteCmd)
+
+	glossaryCreateCmd.Flags().StringVar(&glossaryFormat, "format", "", "format of the entries (tsv, csv)")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// languagesCmd represents the languages command
+var languagesCmd = &cobra.Command{
+	Use:   "languages",
+	Short: "List the languages of the translation service",
+	Long: `List the languages supported by the selected translation service,
+if the service provides such informations.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(printLanguages())
+	},
+}
+
+func printLanguages() error {
+	ctx, cancel := newContext()
+	defer cancel()
+
+	ts, err := selectBackend(translationService)
+	if err != nil {
+		return err
+	}
+
+	languages, err := backend.Languages(ctx, ts)
+	if err != nil {
+		return err
+	}
+	r, err := renderer()
+	if err != nil {
+		return err
+	}
+	return r.Languages(os.Stdout, ts.Name(), languages)
+}
+
+func init() {
+	rootCmd.AddCommand(languagesCmd)
+}
@@ -0,0 +1,237 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const apiVersion = "3.0"
+
+// maxBatchSize is the maximum number of texts Azure accepts in a single request,
+// maxRequestSize the maximum number of characters of these texts, spaces included.
+// https://learn.microsoft.com/azure/ai-services/translator/reference/v3-0-translate
+const (
+	maxBatchSize   = 100
+	maxRequestSize = 10000
+)
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "azure",
+		ConfigKey:   "Azure",
+		Description: "Microsoft Translator, Azure AI services (https://azure.microsoft.com/products/ai-services/ai-translator)",
+		Options: []backend.Option{
+			{Name: "Endpoint", Description: "base URL of the API", Default: "https://api.cognitive.microsofttranslator.com"},
+			{Name: "ApiKey", Description: "subscription key of the resource", Required: true},
+			{Name: "Region", Description: "region of the resource, unless it is global"},
+		},
+		New: func(s backend.Settings) (backend.Backend, error) {
+			return TranslationService{
+				Endpoint:    s.Get("Endpoint"),
+				ApiKey:      s.Get("ApiKey"),
+				Region:      s.Get("Region"),
+				Client:      s.Client,
+				DisplayName: s.Name,
+			}, nil
+		},
+	})
+}
+
+type TranslationService struct {
+	// Endpoint is the base URL of the API, e.g. https://api.cognitive.microsofttranslator.com.
+	Endpoint string
+	ApiKey   string
+	Region   string
+	// Client is the HTTP client to use, http.DefaultClient if nil.
+	Client *http.Client
+	// DisplayName replaces the name of the service, if not empty.
+	DisplayName string
+}
+
+type RequestText struct {
+	Text string `json:"Text"`
+}
+
+type RequestResponse struct {
+	Translations []RequestResponseTranslation `json:"translations"`
+}
+
+type RequestResponseTranslation struct {
+	Text string `json:"text"`
+	To   string `json:"to"`
+}
+
+type RequestLanguages struct {
+	Translation map[string]RequestLanguage `json:"translation"`
+}
+
+type RequestLanguage struct {
+	Name       string `json:"name"`
+	NativeName string `json:"nativeName"`
+}
+
+func (d TranslationService) Name() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
+	return "Azure"
+}
+
+func (d TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	return backend.TranslateOne(ctx, d, text, source, target)
+}
+
+// TranslateBatch translates the texts with as few requests as possible,
+// splitting them by number and by size.
+// The translations are returned in the same order as the texts.
+func (d TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	return backend.ChunkBySize(texts, maxBatchSize, maxRequestSize, utf8.RuneCountInString, func(chunk []string) ([]backend.TranslationResponse, error) {
+		return d.translateBatch(ctx, chunk, source, target)
+	})
+}
+
+// translateBatch translates up to maxBatchSize texts in a single request.
+func (d TranslationService) translateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	inputs := make([]RequestText, len(texts))
+	for i, text := range texts {
+		inputs[i] = RequestText{Text: text}
+	}
+	payload, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("api-version", apiVersion)
+	query.Set("from", checkAzureLanguage(source))
+	query.Set("to", checkAzureLanguage(target))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url("/translate", query), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Ocp-Apim-Subscription-Key", d.ApiKey)
+	if d.Region != "" {
+		req.Header.Add("Ocp-Apim-Subscription-Region", d.Region)
+	}
+
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return nil, checkAzureError(err)
+	}
+
+	var ares []RequestResponse
+	if err := backend.Decode(d.Name(), body, &ares); err != nil {
+		return nil, err
+	}
+	if len(ares) != len(texts) {
+		return nil, backend.NewMalformedResponseError(d.Name(),
+			fmt.Errorf("%d translations received for %d texts", len(ares), len(texts)))
+	}
+
+	responses := make([]backend.TranslationResponse, len(ares))
+	for i, r := range ares {
+		if len(r.Translations) == 0 {
+			return nil, backend.NewMalformedResponseError(d.Name(),
+				fmt.Errorf("no translation received for text %d", i+1))
+		}
+		responses[i] = backend.TranslationResponse{Text: r.Translations[0].Text}
+	}
+	return responses, nil
+}
+
+// Languages returns the languages available for translation, sorted by code.
+func (d TranslationService) Languages(ctx context.Context) ([]backend.Language, error) {
+	query := url.Values{}
+	query.Set("api-version", apiVersion)
+	query.Set("scope", "translation")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url("/languages", query), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return nil, checkAzureError(err)
+	}
+
+	var ares RequestLanguages
+	if err := backend.Decode(d.Name(), body, &ares); err != nil {
+		return nil, err
+	}
+	languages := make([]backend.Language, 0, len(ares.Translation))
+	for code, l := range ares.Translation {
+		languages = append(languages, backend.Language{Code: strings.ToUpper(code), Name: l.Name})
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i].Code < languages[j].Code
+	})
+	return languages, nil
+}
+
+func (d TranslationService) Usage(context.Context) (backend.UsageResponse, error) {
+	return backend.UsageResponse{}, fmt.Errorf("%s: usage: %w", d.Name(), backend.ErrUnsupported)
+}
+
+// url returns the URL of the API path with the query.
+func (d TranslationService) url(path string, query url.Values) string {
+	return strings.TrimSuffix(d.Endpoint, "/") + path + "?" + query.Encode()
+}
+
+// checkAzureLanguage will correct if needed the language
+func checkAzureLanguage(lang string) string {
+	// https://learn.microsoft.com/azure/ai-services/translator/language-support
+	switch lang = strings.ToLower(lang); lang {
+	case "en-gb", "en-us":
+		return "en"
+	case "pt-br":
+		return "pt"
+	case "zh", "zh-hans":
+		return "zh-Hans"
+	case "zh-hant":
+		return "zh-Hant"
+	case "no":
+		return "nb"
+	}
+	return lang
+}
+
+// checkAzureError will correct if needed the kind of error.
+// Azure returns 403 Forbidden with the code 403001 when the free tier quota is exceeded.
+// https://learn.microsoft.com/azure/ai-services/translator/reference/v3-0-reference#errors
+func checkAzureError(err error) error {
+	var berr *backend.Error
+	if !errors.As(err, &berr) || berr.StatusCode != http.StatusForbidden {
+		return err
+	}
+	if strings.Contains(berr.Body, "403001") {
+		berr.Kind = backend.ErrQuotaExceeded
+	}
+	return berr
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/rangzen/t2/pkg/backend"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// translator is a fake Translator API, answering with the texts in upper case.
+type translator struct {
+	t  *testing.T
+	mu sync.Mutex
+	// bodies are the JSON arrays of texts received, one per request.
+	bodies [][]RequestText
+	// header and query are the ones of the last request.
+	header http.Header
+	query  string
+	// status and body replace the translations, if status is set.
+	status int
+	body   string
+}
+
+func (f *translator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var inputs []RequestText
+	if err := json.NewDecoder(r.Body).Decode(&inputs); err != nil {
+		f.t.Error(err)
+	}
+	f.mu.Lock()
+	f.bodies = append(f.bodies, inputs)
+	f.header = r.Header
+	f.query = r.URL.RawQuery
+	f.mu.Unlock()
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_, _ = w.Write([]byte(f.body))
+		return
+	}
+	res := make([]RequestResponse, len(inputs))
+	for i, in := range inputs {
+		res[i].Translations = []RequestResponseTranslation{{Text: strings.ToUpper(in.Text)}}
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func newTranslator(t *testing.T) (*translator, TranslationService) {
+	f := &translator{t: t}
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+	return f, TranslationService{Endpoint: s.URL + "/", ApiKey: "key", Region: "westeurope"}
+}
+
+func TestTranslateRequest(t *testing.T) {
+	f, d := newTranslator(t)
+
+	res, err := d.Translate(context.Background(), "hello", "EN-US", "ZH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Text != "HELLO" {
+		t.Errorf("got %q, want HELLO", res.Text)
+	}
+	if want := "api-version=3.0&from=en&to=zh-Hans"; f.query != want {
+		t.Errorf("got query %s, want %s", f.query, want)
+	}
+	for name, want := range map[string]string{
+		"Content-Type":                 "application/json",
+		"Ocp-Apim-Subscription-Key":    "key",
+		"Ocp-Apim-Subscription-Region": "westeurope",
+	} {
+		if got := f.header.Get(name); got != want {
+			t.Errorf("got %s %q, want %q", name, got, want)
+		}
+	}
+	if want := [][]RequestText{{{Text: "hello"}}}; !reflect.DeepEqual(f.bodies, want) {
+		t.Errorf("got bodies %v, want %v", f.bodies, want)
+	}
+}
+
+func TestTranslateBatchSplits(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		size  int
+		// split is the number of texts of each request.
+		split []int
+	}{
+		{name: "one request", count: 3, size: 10, split: []int{3}},
+		{name: "too many texts", count: maxBatchSize + 2, size: 10, split: []int{maxBatchSize, 2}},
+		{name: "too many characters", count: 3, size: maxRequestSize / 2, split: []int{2, 1}},
+		{name: "text above the limit", count: 2, size: maxRequestSize + 1, split: []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, d := newTranslator(t)
+			texts := make([]string, tt.count)
+			for i := range texts {
+				// Multi-byte characters count once.
+				texts[i] = strings.Repeat("é", tt.size-1) + string(rune('a'+i%26))
+			}
+
+			responses, err := d.TranslateBatch(context.Background(), texts, "FR", "EN-US")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var split []int
+			for _, b := range f.bodies {
+				split = append(split, len(b))
+			}
+			if !reflect.DeepEqual(split, tt.split) {
+				t.Errorf("got requests of %v texts, want %v", split, tt.split)
+			}
+			for i, res := range responses {
+				if res.Text != strings.ToUpper(texts[i]) {
+					t.Errorf("translation %d is not the one of its text", i)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		body string
+		want error
+	}{
+		{body: `{"error":{"code":403001,"message":"free quota exceeded"}}`, want: backend.ErrQuotaExceeded},
+		{body: `{"error":{"code":403000,"message":"forbidden"}}`, want: backend.ErrAuth},
+	}
+	for _, tt := range tests {
+		f, d := newTranslator(t)
+		f.status = http.StatusForbidden
+		f.body = tt.body
+
+		_, err := d.Translate(context.Background(), "hello", "EN-US", "FR")
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.body, err, tt.want)
+		}
+	}
+}
+
+func TestCheckAzureLanguage(t *testing.T) {
+	tests := map[string]string{
+		"EN-US":   "en",
+		"EN-GB":   "en",
+		"FR":      "fr",
+		"PT-BR":   "pt",
+		"ZH":      "zh-Hans",
+		"ZH-HANT": "zh-Hant",
+		"NO":      "nb",
+	}
+	for lang, want := range tests {
+		if got := checkAzureLanguage(lang); got != want {
+			t.Errorf("%s: got %s, want %s", lang, got, want)
+		}
+	}
+}
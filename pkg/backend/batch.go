@@ -0,0 +1,66 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package backend
+
+import "context"
+
+// TranslateOne translates a single text with the TranslateBatch method of b.
+func TranslateOne(ctx context.Context, b Backend, text string, source string, target string) (TranslationResponse, error) {
+	res, err := b.TranslateBatch(ctx, []string{text}, source, target)
+	if err != nil {
+		return TranslationResponse{}, err
+	}
+	return res[0], nil
+}
+
+// Chunk calls fn with the texts by chunks of at most n texts, in order,
+// and returns all the translations in the same order as the texts.
+// On error, the translations of the chunks already done are returned with it.
+func Chunk(texts []string, n int, fn func(chunk []string) ([]TranslationResponse, error)) ([]TranslationResponse, error) {
+	return ChunkBySize(texts, n, 0, nil, fn)
+}
+
+// ChunkBySize is like Chunk, but also keeps the total size of the texts of a chunk,
+// as computed by size, at most maxSize. A text larger than maxSize is sent alone.
+// A maxSize of 0 means no size limit.
+func ChunkBySize(texts []string, n int, maxSize int, size func(text string) int, fn func(chunk []string) ([]TranslationResponse, error)) ([]TranslationResponse, error) {
+	responses := make([]TranslationResponse, 0, len(texts))
+	for start := 0; start < len(texts); {
+		end := start + 1
+		total := 0
+		if maxSize > 0 {
+			total = size(texts[start])
+		}
+		for end < len(texts) && end-start < n {
+			if maxSize > 0 {
+				total += size(texts[end])
+				if total > maxSize {
+					break
+				}
+			}
+			end++
+		}
+		res, err := fn(texts[start:end])
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, res...)
+		start = end
+	}
+	return responses, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package backend
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkBySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		texts   []string
+		n       int
+		maxSize int
+		want    [][]string
+	}{
+		{"by number", []string{"a", "b", "c", "d", "e"}, 2, 0, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"by size", []string{"aa", "bb", "cc", "d"}, 10, 4, [][]string{{"aa", "bb"}, {"cc", "d"}}},
+		{"by number and size", []string{"a", "b", "c", "dddd"}, 2, 4, [][]string{{"a", "b"}, {"c"}, {"dddd"}}},
+		{"larger than size", []string{"a", "bbbbbb", "c"}, 10, 4, [][]string{{"a"}, {"bbbbbb"}, {"c"}}},
+		{"empty", nil, 10, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			res, err := ChunkBySize(tt.texts, tt.n, tt.maxSize, func(text string) int { return len(text) },
+				func(chunk []string) ([]TranslationResponse, error) {
+					got = append(got, chunk)
+					responses := make([]TranslationResponse, len(chunk))
+					for i, text := range chunk {
+						responses[i] = TranslationResponse{Text: strings.ToUpper(text)}
+					}
+					return responses, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got chunks %q, want %q", got, tt.want)
+			}
+			if len(res) != len(tt.texts) {
+				t.Fatalf("got %d translations, want %d", len(res), len(tt.texts))
+			}
+			for i, text := range tt.texts {
+				if res[i].Text != strings.ToUpper(text) {
+					t.Errorf("translation %d: got %q, want %q", i, res[i].Text, strings.ToUpper(text))
+				}
+			}
+		})
+	}
+}
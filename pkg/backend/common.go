@@ -25,3 +25,9 @@ type UsageResponse struct {
 	Used  int64
 	Limit int64
 }
+
+// Language is a language supported by a backend.
+type Language struct {
+	Code string
+	Name string
+}
@@ -18,23 +18,98 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package deepl
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"github.com/rangzen/t2/pkg/backend"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 )
 
-const deeplEndpointUsage = "https://api-free.deepl.com/v2/usage"
+// Translate URLs of the DeepL API, Free for the authentication keys ending with :fx.
+const (
+	deeplEndpointFree = "https://api-free.deepl.com/v2/translate"
+	deeplEndpointPro  = "https://api.deepl.com/v2/translate"
+)
+
+// maxBatchSize is the maximum number of texts DeepL accepts in a single request.
+// https://www.deepl.com/docs-api/translate-text/translate-text/
+const maxBatchSize = 50
+
+// maxRequestSize is the maximum size of a request DeepL accepts.
+// requestOverhead is kept for the parameters other than the texts.
+const (
+	maxRequestSize  = 128 * 1024
+	requestOverhead = 4 * 1024
+)
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "deepl",
+		ConfigKey:   "DeepL",
+		Description: "DeepL API (https://www.deepl.com/pro-api)",
+		Options: []backend.Option{
+			{Name: "Endpoint", Description: "translate or base URL of the API, Free or Pro depending on the ApiKey if empty"},
+			{Name: "ApiKey", Description: "authentication key", Required: true},
+			{Name: "Formality", Description: "default, more, less, prefer_more or prefer_less"},
+			{Name: "GlossaryID", Description: "glossary to use for its language pair"},
+			{Name: "AutoGlossary", Description: "true to use the glossary of the language pair when GlossaryID is empty"},
+			{Name: "SplitSentences", Description: "0, 1 or nonewlines"},
+			{Name: "PreserveFormatting", Description: "true to keep the formatting of the text"},
+			{Name: "TagHandling", Description: "xml or html"},
+			{Name: "IgnoreTags", Description: "comma separated tags whose content is not translated"},
+		},
+		New: func(s backend.Settings) (backend.Backend, error) {
+			return TranslationService{
+				Endpoint:     s.Get("Endpoint"),
+				ApiKey:       s.Get("ApiKey"),
+				Client:       s.Client,
+				DisplayName:  s.Name,
+				AutoGlossary: strings.EqualFold(s.Get("AutoGlossary"), "true"),
+				glossaries:   &glossaryCache{},
+				Options: Options{
+					Formality:          s.Get("Formality"),
+					GlossaryID:         s.Get("GlossaryID"),
+					SplitSentences:     s.Get("SplitSentences"),
+					PreserveFormatting: s.Get("PreserveFormatting"),
+					TagHandling:        s.Get("TagHandling"),
+					IgnoreTags:         s.Get("IgnoreTags"),
+				},
+			}, nil
+		},
+	})
+}
 
 type TranslationService struct {
+	// Endpoint is the translate URL of the API, e.g. https://api.deepl.com/v2/translate,
+	// or its base URL, e.g. https://api.deepl.com/v2. If empty, it depends on ApiKey.
 	Endpoint string
 	ApiKey   string
+	// Client is the HTTP client to use, http.DefaultClient if nil.
+	Client *http.Client
+	// DisplayName replaces the name of the service, if not empty.
+	DisplayName string
+	// Options are sent with every translation request, if not empty.
+	Options Options
+	// AutoGlossary uses the glossary of the language pair when Options.GlossaryID is empty.
+	AutoGlossary bool
+
+	// glossaries are the glossaries of the account, to find the one of a language pair.
+	// Glossaries are sent as configured if nil.
+	glossaries *glossaryCache
+}
+
+// Options are the DeepL specific parameters of the translation requests.
+// https://www.deepl.com/docs-api/translate-text/translate-text/
+type Options struct {
+	Formality          string
+	GlossaryID         string
+	SplitSentences     string
+	PreserveFormatting string
+	TagHandling        string
+	IgnoreTags         string
 }
 
 type RequestResponse struct {
@@ -46,68 +121,131 @@ type RequestResponseTranslation struct {
 	Text                   string `json:"text"`
 }
 
+type RequestLanguage struct {
+	Language          string `json:"language"`
+	Name              string `json:"name"`
+	SupportsFormality bool   `json:"supports_formality"`
+}
+
 type RequestUsage struct {
 	CharacterCount int64 `json:"character_count"`
 	CharacterLimit int64 `json:"character_limit"`
 }
 
 func (d TranslationService) Name() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
 	return "DeepL"
 }
 
-func (d TranslationService) Translate(text string, source string, target string) (backend.TranslationResponse, error) {
-	deeplConfig := d.prepareDeeplConfig(text, source, target)
+func (d TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	return backend.TranslateOne(ctx, d, text, source, target)
+}
+
+// TranslateBatch translates the texts with as few requests as possible,
+// splitting them by number and by size.
+// The translations are returned in the same order as the texts.
+func (d TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	return backend.ChunkBySize(texts, maxBatchSize, maxRequestSize-requestOverhead, encodedSize, func(chunk []string) ([]backend.TranslationResponse, error) {
+		return d.translateBatch(ctx, chunk, source, target)
+	})
+}
 
-	req, err := d.prepareRequest(deeplConfig)
+// encodedSize returns the size of the text as a parameter of the request.
+func encodedSize(text string) int {
+	return len("&text=") + len(url.QueryEscape(text))
+}
+
+// translateBatch translates up to maxBatchSize texts in a single request.
+func (d TranslationService) translateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	glossaryID, err := d.glossaryFor(ctx, source, target)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	deeplConfig := d.prepareDeeplConfig(texts, source, target, glossaryID)
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	req, err := d.newRequest(ctx, http.MethodPost, d.endpoint(), deeplConfig)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(res.Body)
-	body, err := io.ReadAll(res.Body)
+	body, err := backend.Do(d.Name(), d.Client, req)
 	if err != nil {
-		log.Fatal(err)
-	}
-	if res.StatusCode != http.StatusOK {
-		return backend.TranslationResponse{}, errors.New(
-			fmt.Sprint("status:", res.StatusCode, " body:", string(body)),
-		)
+		return nil, err
 	}
 
 	var dres RequestResponse
-	err = json.Unmarshal(body, &dres)
-	if err != nil {
-		log.Fatal(err)
+	if err := backend.Decode(d.Name(), body, &dres); err != nil {
+		return nil, err
+	}
+	if len(dres.Translations) != len(texts) {
+		return nil, backend.NewMalformedResponseError(d.Name(),
+			fmt.Errorf("%d translations received for %d texts", len(dres.Translations), len(texts)))
 	}
 
-	sb := strings.Builder{}
-	for _, t := range dres.Translations {
-		sb.WriteString(t.Text)
+	responses := make([]backend.TranslationResponse, len(dres.Translations))
+	for i, t := range dres.Translations {
+		responses[i] = backend.TranslationResponse{Text: t.Text}
 	}
-	return backend.TranslationResponse{Text: sb.String()}, nil
+	return responses, nil
 }
 
 // prepareDeeplConfig creates the DeepL configuration
-func (d TranslationService) prepareDeeplConfig(text string, source string, target string) url.Values {
+func (d TranslationService) prepareDeeplConfig(texts []string, source string, target string, glossaryID string) url.Values {
 	deeplConfig := url.Values{}
-	deeplConfig.Set("text", text)
+	for _, text := range texts {
+		deeplConfig.Add("text", text)
+	}
 	checkedSource := checkDeeplSource(source)
 	deeplConfig.Set("source_lang", checkedSource)
 	deeplConfig.Set("target_lang", target)
+	options := d.Options
+	options.GlossaryID = glossaryID
+	options.apply(deeplConfig)
 	return deeplConfig
 }
 
+// Variant returns the options, which change the translations,
+// with the glossary used from source to target.
+func (d TranslationService) Variant(ctx context.Context, source string, target string) (string, error) {
+	glossaryID, err := d.glossaryFor(ctx, source, target)
+	if err != nil {
+		return "", err
+	}
+	v := url.Values{}
+	options := d.Options
+	options.GlossaryID = glossaryID
+	options.apply(v)
+	return v.Encode(), nil
+}
+
+// apply sets the options that are not empty.
+func (o Options) apply(deeplConfig url.Values) {
+	set := func(key, value string) {
+		if value != "" {
+			deeplConfig.Set(key, value)
+		}
+	}
+	set("formality", o.Formality)
+	set("glossary_id", o.GlossaryID)
+	set("split_sentences", o.SplitSentences)
+	set("preserve_formatting", checkDeeplBool(o.PreserveFormatting))
+	set("tag_handling", o.TagHandling)
+	set("ignore_tags", o.IgnoreTags)
+}
+
+// checkDeeplBool will convert if needed a boolean to the 0 or 1 expected by DeepL.
+func checkDeeplBool(value string) string {
+	switch strings.ToLower(value) {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return value
+}
+
 // checkDeeplSource will correct if needed the source language
 func checkDeeplSource(source string) string {
 	// DeepL accept EN-GB and EN-US in target language but not as source language.
@@ -118,50 +256,75 @@ func checkDeeplSource(source string) string {
 	return source
 }
 
-// prepareRequest creates the HTTP Request
-func (d TranslationService) prepareRequest(deeplConfig url.Values) (*http.Request, error) {
-	dcEncoded := deeplConfig.Encode()
-	req, err := http.NewRequest(http.MethodPost, d.Endpoint, strings.NewReader(dcEncoded))
+// newRequest creates the HTTP Request, with the values as form if not nil
+func (d TranslationService) newRequest(ctx context.Context, method string, u string, values url.Values) (*http.Request, error) {
+	var body io.Reader
+	encoded := values.Encode()
+	if values != nil {
+		body = strings.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	req.Header.Add("Authorization", "DeepL-Auth-Key "+d.ApiKey)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("Content-Length", strconv.Itoa(len(dcEncoded)))
-	return req, err
+	if values != nil {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Add("Content-Length", strconv.Itoa(len(encoded)))
+	}
+	return req, nil
 }
 
-func (d TranslationService) Usage() (backend.UsageResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, deeplEndpointUsage, nil)
-	if err != nil {
-		log.Fatal(err)
+// endpoint returns the translate URL of the API.
+func (d TranslationService) endpoint() string {
+	return d.apiURL("/translate")
+}
+
+// apiURL returns the URL of the API path.
+// Endpoint is either the translate URL or the base URL of the API, e.g. https://api.deepl.com/v2.
+// Without Endpoint, the authentication keys of the Free API end with :fx.
+func (d TranslationService) apiURL(path string) string {
+	base := d.Endpoint
+	switch {
+	case base != "":
+	case strings.HasSuffix(d.ApiKey, ":fx"):
+		base = deeplEndpointFree
+	default:
+		base = deeplEndpointPro
 	}
-	req.Header.Add("Authorization", "DeepL-Auth-Key "+d.ApiKey)
+	return strings.TrimSuffix(strings.TrimSuffix(base, "/"), "/translate") + path
+}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+func (d TranslationService) Usage(ctx context.Context) (backend.UsageResponse, error) {
+	req, err := d.newRequest(ctx, http.MethodGet, d.apiURL("/usage"), nil)
 	if err != nil {
-		log.Fatal(err)
+		return backend.UsageResponse{}, err
 	}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(res.Body)
-	body, err := io.ReadAll(res.Body)
+	body, err := backend.Do(d.Name(), d.Client, req)
 	if err != nil {
-		log.Fatal(err)
+		return backend.UsageResponse{}, err
 	}
 
 	var dres RequestUsage
-	err = json.Unmarshal(body, &dres)
-	if err != nil {
-		log.Fatal(err)
+	if err := backend.Decode(d.Name(), body, &dres); err != nil {
+		return backend.UsageResponse{}, err
 	}
 	return backend.UsageResponse{
 		Used:  dres.CharacterCount,
 		Limit: dres.CharacterLimit,
 	}, nil
 }
+
+// Languages returns the target languages of the API.
+func (d TranslationService) Languages(ctx context.Context) ([]backend.Language, error) {
+	var dres []RequestLanguage
+	if err := d.call(ctx, http.MethodGet, d.apiURL("/languages?type=target"), nil, &dres); err != nil {
+		return nil, err
+	}
+	languages := make([]backend.Language, len(dres))
+	for i, l := range dres {
+		languages[i] = backend.Language{Code: l.Language, Name: l.Name}
+	}
+	return languages, nil
+}
@@ -0,0 +1,263 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package deepl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		apiKey   string
+		want     string
+	}{
+		{apiKey: "key:fx", want: deeplEndpointFree},
+		{apiKey: "key", want: deeplEndpointPro},
+		{apiKey: "key:fx:other", want: deeplEndpointPro},
+		{endpoint: "http://localhost/v2/translate", apiKey: "key:fx", want: "http://localhost/v2/translate"},
+		{endpoint: "http://localhost/v2/translate/", want: "http://localhost/v2/translate"},
+		{endpoint: "http://localhost/proxy/v2", want: "http://localhost/proxy/v2/translate"},
+		{endpoint: "http://localhost/proxy/v2/", want: "http://localhost/proxy/v2/translate"},
+	}
+	for _, tt := range tests {
+		d := TranslationService{Endpoint: tt.endpoint, ApiKey: tt.apiKey}
+		if got := d.endpoint(); got != tt.want {
+			t.Errorf("endpoint %q with key %q: got %s, want %s", tt.endpoint, tt.apiKey, got, tt.want)
+		}
+	}
+}
+
+// fakeAPI serves the DeepL API under /v2 and keeps the form of each translate request.
+type fakeAPI struct {
+	glossaries []Glossary
+	paths      []string
+	forms      []url.Values
+}
+
+func (f *fakeAPI) start(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/translate", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+			t.Errorf("got Content-Type %q", got)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		f.forms = append(f.forms, r.PostForm)
+		var res RequestResponse
+		for _, text := range r.PostForm["text"] {
+			res.Translations = append(res.Translations, RequestResponseTranslation{Text: "<" + text + ">"})
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	})
+	mux.HandleFunc("/v2/usage", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(RequestUsage{CharacterCount: 42, CharacterLimit: 500000})
+	})
+	mux.HandleFunc("/v2/languages", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]RequestLanguage{{Language: "FR", Name: "French"}})
+	})
+	mux.HandleFunc("/v2/glossaries", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(RequestGlossaries{Glossaries: f.glossaries})
+	})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "DeepL-Auth-Key key" {
+			t.Errorf("got Authorization %q", got)
+		}
+		f.paths = append(f.paths, r.Method+" "+r.URL.RequestURI())
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestCustomEndpoint(t *testing.T) {
+	for _, suffix := range []string{"/v2/translate", "/v2"} {
+		f := &fakeAPI{}
+		d := TranslationService{Endpoint: f.start(t).URL + suffix, ApiKey: "key"}
+		ctx := context.Background()
+
+		if _, err := d.Translate(ctx, "hello", "EN-US", "FR"); err != nil {
+			t.Fatal(err)
+		}
+		if usage, err := d.Usage(ctx); err != nil || usage.Used != 42 || usage.Limit != 500000 {
+			t.Errorf("%s: got usage %+v, %v", suffix, usage, err)
+		}
+		if languages, err := d.Languages(ctx); err != nil || len(languages) != 1 || languages[0].Code != "FR" {
+			t.Errorf("%s: got languages %+v, %v", suffix, languages, err)
+		}
+		if _, err := d.Glossaries(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"POST /v2/translate", "GET /v2/usage", "GET /v2/languages?type=target", "GET /v2/glossaries"}
+		if !reflect.DeepEqual(f.paths, want) {
+			t.Errorf("%s: got requests %q, want %q", suffix, f.paths, want)
+		}
+	}
+}
+
+func TestTranslateForm(t *testing.T) {
+	f := &fakeAPI{}
+	d := TranslationService{
+		Endpoint: f.start(t).URL + "/v2",
+		ApiKey:   "key",
+		Options: Options{
+			Formality:          "less",
+			PreserveFormatting: "true",
+			TagHandling:        "xml",
+			IgnoreTags:         "code,pre",
+		},
+	}
+
+	responses, err := d.TranslateBatch(context.Background(), []string{"one", "two", "three"}, "EN-GB", "FR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"<one>", "<two>", "<three>"} {
+		if responses[i].Text != want {
+			t.Errorf("translation %d: got %q, want %q", i, responses[i].Text, want)
+		}
+	}
+	want := url.Values{
+		"text":                {"one", "two", "three"},
+		"source_lang":         {"EN"},
+		"target_lang":         {"FR"},
+		"formality":           {"less"},
+		"preserve_formatting": {"1"},
+		"tag_handling":        {"xml"},
+		"ignore_tags":         {"code,pre"},
+	}
+	if len(f.forms) != 1 || !reflect.DeepEqual(f.forms[0], want) {
+		t.Errorf("got forms %v, want %v", f.forms, want)
+	}
+}
+
+func TestTranslateBatchLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+	}{
+		{name: "more texts than a request", texts: numbered(2*maxBatchSize+1, "text")},
+		// Every "é" is sent as %C3%A9: 10 of these texts don't fit in a request.
+		{name: "larger than a request", texts: numbered(10, strings.Repeat("é", maxRequestSize/60))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAPI{}
+			d := TranslationService{Endpoint: f.start(t).URL + "/v2", ApiKey: "key"}
+
+			responses, err := d.TranslateBatch(context.Background(), tt.texts, "EN-US", "FR")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.forms) < 2 {
+				t.Errorf("got a single request, want several")
+			}
+			var sent []string
+			for _, form := range f.forms {
+				if n := len(form["text"]); n > maxBatchSize {
+					t.Errorf("got %d texts in a request", n)
+				}
+				if n := len(form.Encode()); n > maxRequestSize {
+					t.Errorf("got a request of %d bytes", n)
+				}
+				sent = append(sent, form["text"]...)
+			}
+			if !reflect.DeepEqual(sent, tt.texts) {
+				t.Error("the texts are not sent once each, in order")
+			}
+			for i, res := range responses {
+				if res.Text != "<"+tt.texts[i]+">" {
+					t.Errorf("translation %d is not the one of its text", i)
+				}
+			}
+		})
+	}
+}
+
+// numbered returns n texts made of the prefix and their index.
+func numbered(n int, prefix string) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprint(prefix, " ", i)
+	}
+	return texts
+}
+
+func TestGlossaryFor(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2021, 9, d, 0, 0, 0, 0, time.UTC)
+	}
+	glossaries := []Glossary{
+		{GlossaryID: "old", Ready: true, SourceLang: "en", TargetLang: "fr", CreationTime: day(1)},
+		{GlossaryID: "recent", Ready: true, SourceLang: "en", TargetLang: "fr", CreationTime: day(2)},
+		{GlossaryID: "pending", Ready: false, SourceLang: "en", TargetLang: "fr", CreationTime: day(3)},
+		{GlossaryID: "german", Ready: true, SourceLang: "en", TargetLang: "de", CreationTime: day(4)},
+	}
+	tests := []struct {
+		name       string
+		glossaryID string
+		auto       bool
+		target     string
+		want       string
+	}{
+		{name: "none", target: "FR", want: ""},
+		{name: "configured", glossaryID: "old", target: "FR", want: "old"},
+		{name: "configured for another pair", glossaryID: "german", target: "FR", want: ""},
+		{name: "configured over automatic", glossaryID: "old", auto: true, target: "FR", want: "old"},
+		{name: "automatic", auto: true, target: "FR", want: "recent"},
+		{name: "automatic without glossary for the pair", auto: true, target: "IT", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeAPI{glossaries: glossaries}
+			d := TranslationService{
+				Endpoint:     f.start(t).URL + "/v2",
+				ApiKey:       "key",
+				Options:      Options{GlossaryID: tt.glossaryID},
+				AutoGlossary: tt.auto,
+				glossaries:   &glossaryCache{},
+			}
+
+			ctx := context.Background()
+			if _, err := d.Translate(ctx, "hello", "EN-US", tt.target); err != nil {
+				t.Fatal(err)
+			}
+			// The glossary is not used on the way back.
+			if _, err := d.Translate(ctx, "hello", tt.target, "EN-US"); err != nil {
+				t.Fatal(err)
+			}
+			if got := f.forms[0].Get("glossary_id"); got != tt.want {
+				t.Errorf("got glossary %q, want %q", got, tt.want)
+			}
+			if got := f.forms[1].Get("glossary_id"); got != "" {
+				t.Errorf("got glossary %q for the way back", got)
+			}
+		})
+	}
+}
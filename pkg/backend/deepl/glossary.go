@@ -0,0 +1,181 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package deepl
+
+import (
+	"context"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Glossary describes a DeepL glossary.
+// https://www.deepl.com/docs-api/glossaries/
+type Glossary struct {
+	GlossaryID   string    `json:"glossary_id"`
+	Name         string    `json:"name"`
+	Ready        bool      `json:"ready"`
+	SourceLang   string    `json:"source_lang"`
+	TargetLang   string    `json:"target_lang"`
+	CreationTime time.Time `json:"creation_time"`
+	EntryCount   int       `json:"entry_count"`
+}
+
+type RequestGlossaries struct {
+	Glossaries []Glossary `json:"glossaries"`
+}
+
+// Matches reports whether the glossary applies to the translation from source to target.
+func (g Glossary) Matches(source string, target string) bool {
+	return strings.EqualFold(g.SourceLang, glossaryLanguage(source)) &&
+		strings.EqualFold(g.TargetLang, glossaryLanguage(target))
+}
+
+// CreateGlossary creates a glossary from the entries, in the tsv or csv format.
+func (d TranslationService) CreateGlossary(ctx context.Context, name string, source string, target string, entries string, format string) (Glossary, error) {
+	values := url.Values{}
+	values.Set("name", name)
+	values.Set("source_lang", glossaryLanguage(source))
+	values.Set("target_lang", glossaryLanguage(target))
+	values.Set("entries", entries)
+	values.Set("entries_format", format)
+
+	var g Glossary
+	err := d.call(ctx, http.MethodPost, d.glossaryURL(""), values, &g)
+	return g, err
+}
+
+// Glossaries returns all the glossaries of the account.
+func (d TranslationService) Glossaries(ctx context.Context) ([]Glossary, error) {
+	var gres RequestGlossaries
+	err := d.call(ctx, http.MethodGet, d.glossaryURL(""), nil, &gres)
+	return gres.Glossaries, err
+}
+
+// Glossary returns the glossary.
+func (d TranslationService) Glossary(ctx context.Context, id string) (Glossary, error) {
+	var g Glossary
+	err := d.call(ctx, http.MethodGet, d.glossaryURL(id), nil, &g)
+	return g, err
+}
+
+// GlossaryEntries returns the entries of the glossary in the tsv format.
+func (d TranslationService) GlossaryEntries(ctx context.Context, id string) (string, error) {
+	req, err := d.newRequest(ctx, http.MethodGet, d.glossaryURL(id)+"/entries", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Add("Accept", "text/tab-separated-values")
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// DeleteGlossary deletes the glossary.
+func (d TranslationService) DeleteGlossary(ctx context.Context, id string) error {
+	return d.call(ctx, http.MethodDelete, d.glossaryURL(id), nil, nil)
+}
+
+// glossaryURL returns the URL of the glossary, or of all the glossaries if id is empty.
+func (d TranslationService) glossaryURL(id string) string {
+	u := d.apiURL("/glossaries")
+	if id != "" {
+		u += "/" + url.PathEscape(id)
+	}
+	return u
+}
+
+// call sends the request and decodes its response into v, if not nil.
+func (d TranslationService) call(ctx context.Context, method string, u string, values url.Values, v interface{}) error {
+	req, err := d.newRequest(ctx, method, u, values)
+	if err != nil {
+		return err
+	}
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	return backend.Decode(d.Name(), body, v)
+}
+
+// glossaryLanguage returns the language as expected by the glossaries,
+// which don't have variants: EN-US is en.
+func glossaryLanguage(lang string) string {
+	return strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+}
+
+// glossaryCache keeps the list of the glossaries, fetched once.
+type glossaryCache struct {
+	mu         sync.Mutex
+	fetched    bool
+	glossaries []Glossary
+}
+
+// glossaryFor returns the glossary to use for the translation from source to target, if any.
+// The configured glossary is used only for its own language pair.
+// Without one, the most recent ready glossary of the language pair is used if AutoGlossary is on.
+func (d TranslationService) glossaryFor(ctx context.Context, source string, target string) (string, error) {
+	if d.glossaries == nil || (d.Options.GlossaryID == "" && !d.AutoGlossary) {
+		return d.Options.GlossaryID, nil
+	}
+	glossaries, err := d.glossaries.get(ctx, d)
+	if err != nil {
+		return "", fmt.Errorf("glossaries: %w", err)
+	}
+
+	if d.Options.GlossaryID != "" {
+		for _, g := range glossaries {
+			if g.GlossaryID == d.Options.GlossaryID && !g.Matches(source, target) {
+				return "", nil
+			}
+		}
+		return d.Options.GlossaryID, nil
+	}
+
+	var best Glossary
+	for _, g := range glossaries {
+		if g.Ready && g.Matches(source, target) && g.CreationTime.After(best.CreationTime) {
+			best = g
+		}
+	}
+	return best.GlossaryID, nil
+}
+
+func (c *glossaryCache) get(ctx context.Context, d TranslationService) ([]Glossary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fetched {
+		return c.glossaries, nil
+	}
+	glossaries, err := d.Glossaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.glossaries = glossaries
+	c.fetched = true
+	return glossaries, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kinds of errors returned by the backends, to check with errors.Is.
+var (
+	ErrAuth                = errors.New("authentication failed")
+	ErrQuotaExceeded       = errors.New("quota exceeded")
+	ErrRateLimited         = errors.New("too many requests")
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrNetwork             = errors.New("network error")
+	ErrMalformedResponse   = errors.New("malformed response")
+	ErrServer              = errors.New("server error")
+	ErrRequest             = errors.New("invalid request")
+)
+
+// Error is an error returned by a backend.
+// Kind is one of the Err variables of the package.
+// RetryAfter is the delay asked by the service before a new request, if any.
+type Error struct {
+	Backend    string
+	Kind       error
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := e.Backend + ": " + e.Kind.Error()
+	if e.StatusCode != 0 {
+		msg += fmt.Sprint(" (status ", e.StatusCode, ")")
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Is reports whether the kind of the error is target.
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewStatusError returns the error matching the HTTP status code of a response.
+func NewStatusError(service string, statusCode int, body []byte) *Error {
+	return &Error{
+		Backend:    service,
+		Kind:       statusKind(statusCode, string(body)),
+		StatusCode: statusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+}
+
+// Retryable reports whether the request may succeed if sent again later.
+func Retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer) || errors.Is(err, ErrNetwork)
+}
+
+// NewNetworkError returns an error for a request that did not get a response.
+func NewNetworkError(service string, err error) *Error {
+	return &Error{Backend: service, Kind: ErrNetwork, Err: err}
+}
+
+// NewMalformedResponseError returns an error for a response that can't be decoded.
+func NewMalformedResponseError(service string, err error) *Error {
+	return &Error{Backend: service, Kind: ErrMalformedResponse, Err: err}
+}
+
+func statusKind(statusCode int, body string) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == 456:
+		// DeepL specific status code.
+		return ErrQuotaExceeded
+	case statusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(body), "lang"):
+		return ErrUnsupportedLanguage
+	case statusCode >= 500:
+		return ErrServer
+	default:
+		return ErrRequest
+	}
+}
@@ -18,21 +18,47 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package google
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/rangzen/t2/pkg/backend"
-	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 )
 
+// maxBatchSize is the maximum number of texts Google recommends in a single request.
+// https://cloud.google.com/translate/docs/reference/rest/v2/translate
+const maxBatchSize = 128
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "google",
+		ConfigKey:   "Google",
+		Description: "Google Cloud Translation, Basic edition (https://cloud.google.com/translate)",
+		Options: []backend.Option{
+			{Name: "Endpoint", Description: "translate URL of the API", Required: true},
+			{Name: "ApiKey", Description: "API key without restriction", Required: true},
+		},
+		New: func(s backend.Settings) (backend.Backend, error) {
+			return TranslationService{
+				Endpoint:    s.Get("Endpoint"),
+				ApiKey:      s.Get("ApiKey"),
+				Client:      s.Client,
+				DisplayName: s.Name,
+			}, nil
+		},
+	})
+}
+
 type TranslationService struct {
 	Endpoint string
 	ApiKey   string
+	// Client is the HTTP client to use, http.DefaultClient if nil.
+	Client *http.Client
+	// DisplayName replaces the name of the service, if not empty.
+	DisplayName string
 }
 
 type RequestResponse struct {
@@ -55,56 +81,60 @@ type RequestUsage struct {
 }
 
 func (d TranslationService) Name() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
 	return "Google"
 }
 
-func (d TranslationService) Translate(text string, source string, target string) (backend.TranslationResponse, error) {
-	googleConfig := d.prepareGoogleConfig(text, source, target)
+func (d TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	return backend.TranslateOne(ctx, d, text, source, target)
+}
+
+// TranslateBatch translates the texts with as few requests as possible.
+// The translations are returned in the same order as the texts.
+func (d TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	return backend.Chunk(texts, maxBatchSize, func(chunk []string) ([]backend.TranslationResponse, error) {
+		return d.translateBatch(ctx, chunk, source, target)
+	})
+}
 
-	req, err := d.prepareRequest(googleConfig)
-	if err != nil {
-		log.Fatal(err)
-	}
+// translateBatch translates up to maxBatchSize texts in a single request.
+func (d TranslationService) translateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	googleConfig := d.prepareGoogleConfig(texts, source, target)
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+	req, err := d.prepareRequest(ctx, googleConfig)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(res.Body)
-	body, err := io.ReadAll(res.Body)
+	body, err := backend.Do(d.Name(), d.Client, req)
 	if err != nil {
-		log.Fatal(err)
-	}
-	if res.StatusCode != http.StatusOK {
-		return backend.TranslationResponse{}, errors.New(
-			fmt.Sprint("status:", res.StatusCode, " body:", string(body)),
-		)
+		return nil, checkGoogleError(err)
 	}
 
 	var dres RequestResponse
-	err = json.Unmarshal(body, &dres)
-	if err != nil {
-		log.Fatal(err)
+	if err := backend.Decode(d.Name(), body, &dres); err != nil {
+		return nil, err
+	}
+	if len(dres.Data.Translations) != len(texts) {
+		return nil, backend.NewMalformedResponseError(d.Name(),
+			fmt.Errorf("%d translations received for %d texts", len(dres.Data.Translations), len(texts)))
 	}
 
-	sb := strings.Builder{}
-	for _, t := range dres.Data.Translations {
-		sb.WriteString(t.Text)
+	responses := make([]backend.TranslationResponse, len(dres.Data.Translations))
+	for i, t := range dres.Data.Translations {
+		responses[i] = backend.TranslationResponse{Text: t.Text}
 	}
-	return backend.TranslationResponse{Text: sb.String()}, nil
+	return responses, nil
 }
 
-// prepareGoogleConfig creates the DeepL configuration
-func (d TranslationService) prepareGoogleConfig(text string, source string, target string) url.Values {
+// prepareGoogleConfig creates the Google configuration
+func (d TranslationService) prepareGoogleConfig(texts []string, source string, target string) url.Values {
 	deeplConfig := url.Values{}
-	deeplConfig.Set("q", text)
+	for _, text := range texts {
+		deeplConfig.Add("q", text)
+	}
 	checkedTarget := checkGoogleLanguage(target)
 	deeplConfig.Set("target", checkedTarget)
 	deeplConfig.Set("format", "text")
@@ -125,17 +155,34 @@ func checkGoogleLanguage(source string) string {
 }
 
 // prepareRequest creates the HTTP Request
-func (d TranslationService) prepareRequest(config url.Values) (*http.Request, error) {
+func (d TranslationService) prepareRequest(ctx context.Context, config url.Values) (*http.Request, error) {
 	dcEncoded := config.Encode()
-	req, err := http.NewRequest(http.MethodPost, d.Endpoint, strings.NewReader(dcEncoded))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Endpoint, strings.NewReader(dcEncoded))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("Content-Length", strconv.Itoa(len(dcEncoded)))
-	return req, err
+	return req, nil
+}
+
+// checkGoogleError will correct if needed the kind of error.
+// Google returns 403 Forbidden when a quota or a rate limit is exceeded.
+// https://cloud.google.com/translate/docs/reference/rest/v2/translate
+func checkGoogleError(err error) error {
+	var berr *backend.Error
+	if !errors.As(err, &berr) || berr.StatusCode != http.StatusForbidden {
+		return err
+	}
+	switch {
+	case strings.Contains(berr.Body, "dailyLimitExceeded") || strings.Contains(berr.Body, "quotaExceeded"):
+		berr.Kind = backend.ErrQuotaExceeded
+	case strings.Contains(berr.Body, "RateLimitExceeded") || strings.Contains(berr.Body, "rateLimitExceeded"):
+		berr.Kind = backend.ErrRateLimited
+	}
+	return berr
 }
 
-func (d TranslationService) Usage() (backend.UsageResponse, error) {
-	return backend.UsageResponse{}, errors.New("Check Google Cloud Console for usages.")
+func (d TranslationService) Usage(context.Context) (backend.UsageResponse, error) {
+	return backend.UsageResponse{}, fmt.Errorf("%s: usage: %w", d.Name(), backend.ErrUnsupported)
 }
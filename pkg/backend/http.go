@@ -0,0 +1,83 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Do sends the request with the client, or http.DefaultClient if nil,
+// and returns the body of the response.
+// Network failures and non 2xx status codes are returned as *Error.
+// If the context of the request is done, its error is returned.
+func Do(service string, client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, NewNetworkError(service, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, NewNetworkError(service, err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		err := NewStatusError(service, res.StatusCode, body)
+		err.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseRetryAfter returns the delay of a Retry-After header,
+// given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Decode parses the JSON body of a response into v.
+func Decode(service string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return NewMalformedResponseError(service, err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package libretranslate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"net/http"
+	"strings"
+)
+
+// maxBatchSize is the number of texts sent in a single request.
+// LibreTranslate has no limit by default, but a server can set one with --batch-limit.
+// https://github.com/LibreTranslate/LibreTranslate#arguments
+const maxBatchSize = 50
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "libretranslate",
+		ConfigKey:   "LibreTranslate",
+		Description: "LibreTranslate, self-hosted or not (https://libretranslate.com)",
+		Options: []backend.Option{
+			{Name: "Endpoint", Description: "base URL of the server", Default: "http://localhost:5000"},
+			{Name: "ApiKey", Description: "API key, if the server requires one"},
+		},
+		New: func(s backend.Settings) (backend.Backend, error) {
+			return TranslationService{
+				Endpoint:    s.Get("Endpoint"),
+				ApiKey:      s.Get("ApiKey"),
+				Client:      s.Client,
+				DisplayName: s.Name,
+			}, nil
+		},
+	})
+}
+
+type TranslationService struct {
+	// Endpoint is the base URL of the server, e.g. http://localhost:5000.
+	Endpoint string
+	ApiKey   string
+	// Client is the HTTP client to use, http.DefaultClient if nil.
+	Client *http.Client
+	// DisplayName replaces the name of the service, if not empty.
+	DisplayName string
+}
+
+type RequestTranslate struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	ApiKey string   `json:"api_key,omitempty"`
+}
+
+type RequestResponse struct {
+	Text []string `json:"translatedText"`
+}
+
+type RequestLanguage struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func (d TranslationService) Name() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
+	return "LibreTranslate"
+}
+
+func (d TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	return backend.TranslateOne(ctx, d, text, source, target)
+}
+
+// TranslateBatch translates the texts with as few requests as possible.
+// The translations are returned in the same order as the texts.
+func (d TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	return backend.Chunk(texts, maxBatchSize, func(chunk []string) ([]backend.TranslationResponse, error) {
+		return d.translateBatch(ctx, chunk, source, target)
+	})
+}
+
+// translateBatch translates up to maxBatchSize texts in a single request.
+func (d TranslationService) translateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	payload, err := json.Marshal(RequestTranslate{
+		Q:      texts,
+		Source: checkLibreLanguage(source),
+		Target: checkLibreLanguage(target),
+		Format: "text",
+		ApiKey: d.ApiKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url("/translate"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return nil, checkLibreError(err)
+	}
+
+	var lres RequestResponse
+	if err := backend.Decode(d.Name(), body, &lres); err != nil {
+		return nil, err
+	}
+	if len(lres.Text) != len(texts) {
+		return nil, backend.NewMalformedResponseError(d.Name(),
+			fmt.Errorf("%d translations received for %d texts", len(lres.Text), len(texts)))
+	}
+
+	responses := make([]backend.TranslationResponse, len(lres.Text))
+	for i, t := range lres.Text {
+		responses[i] = backend.TranslationResponse{Text: t}
+	}
+	return responses, nil
+}
+
+// Languages returns the languages available on the server.
+func (d TranslationService) Languages(ctx context.Context) ([]backend.Language, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url("/languages"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return nil, checkLibreError(err)
+	}
+
+	var lres []RequestLanguage
+	if err := backend.Decode(d.Name(), body, &lres); err != nil {
+		return nil, err
+	}
+	languages := make([]backend.Language, len(lres))
+	for i, l := range lres {
+		languages[i] = backend.Language{Code: strings.ToUpper(l.Code), Name: l.Name}
+	}
+	return languages, nil
+}
+
+func (d TranslationService) Usage(context.Context) (backend.UsageResponse, error) {
+	return backend.UsageResponse{}, fmt.Errorf("%s: usage: %w", d.Name(), backend.ErrUnsupported)
+}
+
+// url returns the URL of the API path on the server.
+func (d TranslationService) url(path string) string {
+	return strings.TrimSuffix(d.Endpoint, "/") + path
+}
+
+// checkLibreLanguage will correct if needed the language
+func checkLibreLanguage(lang string) string {
+	// https://libretranslate.com/languages
+	switch lang = strings.ToLower(lang); lang {
+	case "en-gb", "en-us":
+		return "en"
+	case "pt-pt", "pt-br":
+		return "pt"
+	case "zh-hans":
+		return "zh"
+	case "zh-hant":
+		return "zt"
+	}
+	return lang
+}
+
+// checkLibreError will correct if needed the kind of error.
+// LibreTranslate returns 400 Bad Request with "xx is not supported" for an unknown language.
+func checkLibreError(err error) error {
+	var berr *backend.Error
+	if !errors.As(err, &berr) || berr.StatusCode != http.StatusBadRequest {
+		return err
+	}
+	if strings.Contains(berr.Body, "not supported") {
+		berr.Kind = backend.ErrUnsupportedLanguage
+	}
+	return berr
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mock
+
+import (
+	"context"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "mock",
+		ConfigKey:   "Mock",
+		Description: "offline and deterministic translations, for tests and demos",
+		Options: []backend.Option{
+			{Name: "Fixture", Description: "YAML or JSON file of canned translations and rules, relative to the configuration file"},
+		},
+		Offline: true,
+		New: func(s backend.Settings) (backend.Backend, error) {
+			m, err := Open(s.Path("Fixture"))
+			m.DisplayName = s.Name
+			return m, err
+		},
+	})
+}
+
+// TranslationService is a deterministic backend working offline, for tests and demos.
+// A text is translated with the first matching canned translation, otherwise
+// with the rules of the target language, otherwise it is returned as is.
+type TranslationService struct {
+	// DisplayName replaces the name of the service, if not empty.
+	DisplayName string
+
+	translations map[string]string
+	rules        []rule
+}
+
+// Fixture is the content of a fixture file.
+type Fixture struct {
+	Translations []Translation `yaml:"Translations"`
+	Rules        []Rule        `yaml:"Rules"`
+}
+
+// Translation is a canned translation of a text.
+type Translation struct {
+	Source      string `yaml:"Source"`
+	Target      string `yaml:"Target"`
+	Text        string `yaml:"Text"`
+	Translation string `yaml:"Translation"`
+}
+
+// Rule is a scripted transformation: every match of Pattern is replaced by Replace
+// when translating to Target, or to any language if Target is empty.
+// Replace can use the $1 syntax of regexp.Regexp.ReplaceAllString.
+type Rule struct {
+	Target  string `yaml:"Target"`
+	Pattern string `yaml:"Pattern"`
+	Replace string `yaml:"Replace"`
+}
+
+type rule struct {
+	target  string
+	pattern *regexp.Regexp
+	replace string
+}
+
+// New returns the backend for the fixture.
+func New(f Fixture) (TranslationService, error) {
+	m := TranslationService{translations: map[string]string{}}
+	for _, t := range f.Translations {
+		m.translations[key(t.Source, t.Target, t.Text)] = t.Translation
+	}
+	for _, r := range f.Rules {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return TranslationService{}, fmt.Errorf("invalid rule %q: %w", r.Pattern, err)
+		}
+		m.rules = append(m.rules, rule{target: strings.ToUpper(r.Target), pattern: pattern, replace: r.Replace})
+	}
+	return m, nil
+}
+
+// Open returns the backend for a YAML or JSON fixture file.
+// Without file, the texts are returned as is.
+func Open(path string) (TranslationService, error) {
+	if path == "" {
+		return New(Fixture{})
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return TranslationService{}, err
+	}
+	var f Fixture
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return TranslationService{}, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return New(f)
+}
+
+func (m TranslationService) Name() string {
+	if m.DisplayName != "" {
+		return m.DisplayName
+	}
+	return "Mock"
+}
+
+func (m TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return backend.TranslationResponse{}, err
+	}
+	if t, ok := m.translations[key(source, target, text)]; ok {
+		return backend.TranslationResponse{Text: t}, nil
+	}
+	for _, r := range m.rules {
+		if r.target == "" || r.target == strings.ToUpper(target) {
+			text = r.pattern.ReplaceAllString(text, r.replace)
+		}
+	}
+	return backend.TranslationResponse{Text: text}, nil
+}
+
+func (m TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	responses := make([]backend.TranslationResponse, len(texts))
+	for i, text := range texts {
+		res, err := m.Translate(ctx, text, source, target)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = res
+	}
+	return responses, nil
+}
+
+func (m TranslationService) Usage(context.Context) (backend.UsageResponse, error) {
+	return backend.UsageResponse{}, nil
+}
+
+func key(source, target, text string) string {
+	return strings.ToUpper(source) + "\x00" + strings.ToUpper(target) + "\x00" + text
+}
@@ -0,0 +1,260 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultSystemPrompt asks the model for the translation only.
+// {source} and {target} are replaced by the names of the languages.
+const DefaultSystemPrompt = "You are a translation engine. Translate the text of the user from {source} to {target}. " +
+	"Reply with the translation only, without quotes, notes or explanations."
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "openai",
+		ConfigKey:   "OpenAI",
+		Description: "large language model behind an OpenAI-compatible chat completions API, e.g. llama.cpp or Ollama",
+		Options: []backend.Option{
+			{Name: "Endpoint", Description: "base URL of the API, without /v1", Default: "http://localhost:11434"},
+			{Name: "ApiKey", Description: "bearer token, if the server requires one"},
+			{Name: "Model", Description: "name of the model", Required: true},
+			{Name: "Temperature", Description: "sampling temperature", Default: "0"},
+			{Name: "SystemPrompt", Description: "instructions, with {source} and {target} replaced by the languages", Default: DefaultSystemPrompt},
+		},
+		New: func(s backend.Settings) (backend.Backend, error) {
+			temperature, err := strconv.ParseFloat(s.Get("Temperature"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid temperature %q: %w", s.Get("Temperature"), err)
+			}
+			return &TranslationService{
+				Endpoint:     s.Get("Endpoint"),
+				ApiKey:       s.Get("ApiKey"),
+				Model:        s.Get("Model"),
+				Temperature:  temperature,
+				SystemPrompt: s.Get("SystemPrompt"),
+				Client:       s.Client,
+				DisplayName:  s.Name,
+			}, nil
+		},
+	})
+}
+
+// TranslationService asks a model to translate each text with a chat completion.
+// It counts the tokens used by its requests.
+type TranslationService struct {
+	// Endpoint is the base URL of the API, e.g. http://localhost:11434.
+	Endpoint     string
+	ApiKey       string
+	Model        string
+	Temperature  float64
+	SystemPrompt string
+	// Client is the HTTP client to use, http.DefaultClient if nil.
+	Client *http.Client
+	// DisplayName replaces the name of the service, if not empty.
+	DisplayName string
+
+	tokens int64
+}
+
+type RequestChat struct {
+	Model       string           `json:"model"`
+	Messages    []RequestMessage `json:"messages"`
+	Temperature float64          `json:"temperature"`
+	Stream      bool             `json:"stream"`
+}
+
+type RequestMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type RequestResponse struct {
+	Choices []RequestResponseChoice `json:"choices"`
+	Usage   RequestResponseUsage    `json:"usage"`
+}
+
+type RequestResponseChoice struct {
+	Message RequestMessage `json:"message"`
+}
+
+type RequestResponseUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// Name returns the name of the model, so that each model has its own cached translations.
+func (d *TranslationService) Name() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
+	return d.Model
+}
+
+func (d *TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	chat := RequestChat{
+		Model: d.Model,
+		Messages: []RequestMessage{
+			{Role: "system", Content: d.systemPrompt(source, target)},
+			{Role: "user", Content: text},
+		},
+		Temperature: d.Temperature,
+	}
+	payload, err := json.Marshal(chat)
+	if err != nil {
+		return backend.TranslationResponse{}, err
+	}
+	url := strings.TrimSuffix(d.Endpoint, "/") + "/v1/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return backend.TranslationResponse{}, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if d.ApiKey != "" {
+		req.Header.Add("Authorization", "Bearer "+d.ApiKey)
+	}
+
+	body, err := backend.Do(d.Name(), d.Client, req)
+	if err != nil {
+		return backend.TranslationResponse{}, checkOpenAIError(err)
+	}
+
+	var ores RequestResponse
+	if err := backend.Decode(d.Name(), body, &ores); err != nil {
+		return backend.TranslationResponse{}, err
+	}
+	if len(ores.Choices) == 0 {
+		return backend.TranslationResponse{}, backend.NewMalformedResponseError(d.Name(), errors.New("no choice received"))
+	}
+	tokens := ores.Usage.TotalTokens
+	if tokens == 0 {
+		tokens = ores.Usage.PromptTokens + ores.Usage.CompletionTokens
+	}
+	atomic.AddInt64(&d.tokens, tokens)
+	return backend.TranslationResponse{Text: strings.TrimSpace(ores.Choices[0].Message.Content)}, nil
+}
+
+// Variant returns the temperature and the system prompt, which change the translations.
+func (d *TranslationService) Variant(context.Context, string, string) (string, error) {
+	return strconv.FormatFloat(d.Temperature, 'g', -1, 64) + "\x00" + d.SystemPrompt, nil
+}
+
+// TranslateBatch translates the texts one by one, a model is not reliable
+// enough to keep apart several texts of a single prompt.
+func (d *TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	responses := make([]backend.TranslationResponse, len(texts))
+	for i, text := range texts {
+		res, err := d.Translate(ctx, text, source, target)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = res
+	}
+	return responses, nil
+}
+
+// Usage returns the number of tokens used by the requests of this process.
+// There is no limit. The API doesn't report the usage of the account,
+// so before the first request, it returns backend.ErrUnsupported.
+func (d *TranslationService) Usage(context.Context) (backend.UsageResponse, error) {
+	tokens := atomic.LoadInt64(&d.tokens)
+	if tokens == 0 {
+		return backend.UsageResponse{}, fmt.Errorf("%s: usage of the account: %w", d.Name(), backend.ErrUnsupported)
+	}
+	return backend.UsageResponse{Used: tokens}, nil
+}
+
+// systemPrompt returns the system prompt for the languages.
+func (d *TranslationService) systemPrompt(source string, target string) string {
+	return strings.NewReplacer(
+		"{source}", languageName(source),
+		"{target}", languageName(target),
+	).Replace(d.SystemPrompt)
+}
+
+// languageNames are the names of the languages given to the model,
+// which understands them better than codes.
+var languageNames = map[string]string{
+	"AR":    "Arabic",
+	"BG":    "Bulgarian",
+	"CS":    "Czech",
+	"DA":    "Danish",
+	"DE":    "German",
+	"EL":    "Greek",
+	"EN":    "English",
+	"EN-GB": "British English",
+	"EN-US": "American English",
+	"ES":    "Spanish",
+	"ET":    "Estonian",
+	"FI":    "Finnish",
+	"FR":    "French",
+	"HU":    "Hungarian",
+	"ID":    "Indonesian",
+	"IT":    "Italian",
+	"JA":    "Japanese",
+	"KO":    "Korean",
+	"LT":    "Lithuanian",
+	"LV":    "Latvian",
+	"NB":    "Norwegian",
+	"NL":    "Dutch",
+	"PL":    "Polish",
+	"PT":    "Portuguese",
+	"PT-BR": "Brazilian Portuguese",
+	"PT-PT": "European Portuguese",
+	"RO":    "Romanian",
+	"RU":    "Russian",
+	"SK":    "Slovak",
+	"SL":    "Slovenian",
+	"SV":    "Swedish",
+	"TR":    "Turkish",
+	"UK":    "Ukrainian",
+	"ZH":    "Chinese",
+}
+
+// languageName returns the name of the language, or its code if unknown.
+func languageName(code string) string {
+	if name, ok := languageNames[strings.ToUpper(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// checkOpenAIError will correct if needed the kind of error.
+// OpenAI returns 429 Too Many Requests with insufficient_quota when the quota is exceeded.
+// https://platform.openai.com/docs/guides/error-codes/api-errors
+func checkOpenAIError(err error) error {
+	var berr *backend.Error
+	if !errors.As(err, &berr) || berr.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+	if strings.Contains(berr.Body, "insufficient_quota") {
+		berr.Kind = backend.ErrQuotaExceeded
+	}
+	return berr
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"os/exec"
+	"strings"
+)
+
+// ProtocolVersion is the version of the protocol sent in each request.
+const ProtocolVersion = 1
+
+func init() {
+	backend.Register(backend.Registration{
+		Name:        "plugin",
+		ConfigKey:   "Plugin",
+		Description: "external program speaking JSON over stdin and stdout",
+		Options: []backend.Option{
+			{Name: "Command", Description: "path of the program", Required: true},
+			{Name: "Args", Description: "arguments of the program, separated by spaces"},
+			{Name: "Name", Description: "name displayed in the results, unless the section has a Type", Default: "Plugin"},
+		},
+		New: func(s backend.Settings) (backend.Backend, error) {
+			name := s.Name
+			if name == "" {
+				name = s.Get("Name")
+			}
+			return TranslationService{
+				Command:     s.Get("Command"),
+				Args:        strings.Fields(s.Get("Args")),
+				DisplayName: name,
+			}, nil
+		},
+	})
+}
+
+// TranslationService runs an external program for each call.
+// The program reads one Request as JSON on its standard input
+// and writes one Response as JSON on its standard output.
+type TranslationService struct {
+	Command     string
+	Args        []string
+	DisplayName string
+}
+
+// Request is sent to the program. Method is one of
+// "translate", "translate_batch", "usage" or "languages".
+type Request struct {
+	Version int      `json:"version"`
+	Method  string   `json:"method"`
+	Text    string   `json:"text,omitempty"`
+	Texts   []string `json:"texts,omitempty"`
+	Source  string   `json:"source,omitempty"`
+	Target  string   `json:"target,omitempty"`
+}
+
+// Response is returned by the program, with the fields matching the method,
+// or with Error on failure.
+type Response struct {
+	Text      string             `json:"text,omitempty"`
+	Texts     []string           `json:"texts,omitempty"`
+	Used      int64              `json:"used,omitempty"`
+	Limit     int64              `json:"limit,omitempty"`
+	Languages []ResponseLanguage `json:"languages,omitempty"`
+	Error     *ResponseError     `json:"error,omitempty"`
+}
+
+type ResponseLanguage struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// ResponseError is a failure of the program. Kind is one of
+// "auth", "quota_exceeded", "rate_limited", "unsupported_language",
+// "network", "server" or "request".
+type ResponseError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+var errorKinds = map[string]error{
+	"auth":                 backend.ErrAuth,
+	"quota_exceeded":       backend.ErrQuotaExceeded,
+	"rate_limited":         backend.ErrRateLimited,
+	"unsupported_language": backend.ErrUnsupportedLanguage,
+	"network":              backend.ErrNetwork,
+	"server":               backend.ErrServer,
+	"request":              backend.ErrRequest,
+	"unsupported":          backend.ErrUnsupported,
+}
+
+func (p TranslationService) Name() string {
+	return p.DisplayName
+}
+
+func (p TranslationService) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	res, err := p.call(ctx, Request{Method: "translate", Text: text, Source: source, Target: target})
+	if err != nil {
+		return backend.TranslationResponse{}, err
+	}
+	return backend.TranslationResponse{Text: res.Text}, nil
+}
+
+func (p TranslationService) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	res, err := p.call(ctx, Request{Method: "translate_batch", Texts: texts, Source: source, Target: target})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Texts) != len(texts) {
+		return nil, backend.NewMalformedResponseError(p.Name(),
+			fmt.Errorf("%d translations received for %d texts", len(res.Texts), len(texts)))
+	}
+	responses := make([]backend.TranslationResponse, len(res.Texts))
+	for i, t := range res.Texts {
+		responses[i] = backend.TranslationResponse{Text: t}
+	}
+	return responses, nil
+}
+
+func (p TranslationService) Usage(ctx context.Context) (backend.UsageResponse, error) {
+	res, err := p.call(ctx, Request{Method: "usage"})
+	if err != nil {
+		return backend.UsageResponse{}, err
+	}
+	return backend.UsageResponse{Used: res.Used, Limit: res.Limit}, nil
+}
+
+func (p TranslationService) Languages(ctx context.Context) ([]backend.Language, error) {
+	res, err := p.call(ctx, Request{Method: "languages"})
+	if err != nil {
+		return nil, err
+	}
+	languages := make([]backend.Language, len(res.Languages))
+	for i, l := range res.Languages {
+		languages[i] = backend.Language{Code: l.Code, Name: l.Name}
+	}
+	return languages, nil
+}
+
+// call runs the program with the request and returns its response.
+func (p TranslationService) call(ctx context.Context, req Request) (Response, error) {
+	req.Version = ProtocolVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(in)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Response{}, ctxErr
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return Response{}, fmt.Errorf("%s: %w", p.Name(), err)
+		}
+		// The program may have described the failure in its response.
+		if res, decodeErr := decode(p.Name(), stdout.Bytes()); decodeErr != nil || res.Error == nil {
+			return Response{}, fmt.Errorf("%s: %w: %s", p.Name(), err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	res, err := decode(p.Name(), stdout.Bytes())
+	if err != nil {
+		return Response{}, err
+	}
+	if res.Error != nil {
+		kind, ok := errorKinds[res.Error.Kind]
+		if !ok {
+			kind = backend.ErrRequest
+		}
+		return Response{}, &backend.Error{Backend: p.Name(), Kind: kind, Body: res.Error.Message}
+	}
+	return res, nil
+}
+
+func decode(service string, out []byte) (Response, error) {
+	var res Response
+	err := backend.Decode(service, out, &res)
+	return res, err
+}
@@ -0,0 +1,222 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Backend is the interface that wraps the translation backend methods.
+// On error, TranslateBatch may also return the translations of the first texts,
+// already done, so that they are not requested again.
+type Backend interface {
+	Name() string
+	Translate(ctx context.Context, text string, source string, pivot string) (TranslationResponse, error)
+	TranslateBatch(ctx context.Context, texts []string, source string, pivot string) ([]TranslationResponse, error)
+	Usage(ctx context.Context) (UsageResponse, error)
+}
+
+// LanguageLister is implemented by the backends able to list their supported languages.
+type LanguageLister interface {
+	Languages(ctx context.Context) ([]Language, error)
+}
+
+// Varianter is implemented by the backends whose options change the translations,
+// like the formality or a glossary. Variant returns a string identifying these options
+// for the translations from source to target.
+type Varianter interface {
+	Variant(ctx context.Context, source string, target string) (string, error)
+}
+
+// Wrapper is implemented by the backends adding a feature to another backend, like retries.
+type Wrapper interface {
+	Unwrap() Backend
+}
+
+// ErrUnsupported is returned when a backend doesn't provide a feature.
+var ErrUnsupported = errors.New("not supported by the translation service")
+
+// Languages returns the languages supported by the backend, or by the backend it wraps.
+func Languages(ctx context.Context, b Backend) ([]Language, error) {
+	for {
+		if l, ok := b.(LanguageLister); ok {
+			return l.Languages(ctx)
+		}
+		w, ok := b.(Wrapper)
+		if !ok {
+			return nil, fmt.Errorf("%s: languages: %w", b.Name(), ErrUnsupported)
+		}
+		b = w.Unwrap()
+	}
+}
+
+// Variant returns the variant of the backend, or of the backend it wraps, from source to target.
+// It is empty if the options of the backend don't change the translations.
+func Variant(ctx context.Context, b Backend, source string, target string) (string, error) {
+	for {
+		if v, ok := b.(Varianter); ok {
+			return v.Variant(ctx, source, target)
+		}
+		w, ok := b.(Wrapper)
+		if !ok {
+			return "", nil
+		}
+		b = w.Unwrap()
+	}
+}
+
+// Option describes a configuration key of a backend.
+type Option struct {
+	// Name is the key under the section of the backend, e.g. "Endpoint".
+	Name        string
+	Description string
+	Required    bool
+	Default     string
+}
+
+// Settings are the configuration of a backend.
+type Settings struct {
+	// Values are the configuration values by option name, case-insensitive.
+	Values map[string]string
+	// Client is the HTTP client shared by the backends.
+	Client *http.Client
+	// Dir is the directory of the configuration file, for the relative paths of the values.
+	Dir string
+	// Name is the name of the instance declared by a section with a Type,
+	// displayed and cached instead of the name of the service. Empty otherwise.
+	Name string
+}
+
+// Path returns the value of the option as a path, relative to Dir if not absolute.
+func (s Settings) Path(name string) string {
+	path := s.Get(name)
+	if path == "" || filepath.IsAbs(path) || s.Dir == "" {
+		return path
+	}
+	return filepath.Join(s.Dir, path)
+}
+
+// Get returns the value of the option.
+func (s Settings) Get(name string) string {
+	if v, ok := s.Values[name]; ok {
+		return v
+	}
+	for k, v := range s.Values {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Registration describes a backend and how to create it.
+type Registration struct {
+	// Name selects the backend, e.g. with the --translation-service flag.
+	Name string
+	// ConfigKey is the section of the backend under TranslationServices in the configuration file.
+	ConfigKey   string
+	Description string
+	Options     []Option
+	// Offline backends don't need retries nor cache.
+	Offline bool
+	New     func(Settings) (Backend, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Registration{}
+)
+
+// Register makes a backend available by its name.
+// It panics if the name is already registered.
+func Register(r Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := strings.ToLower(r.Name)
+	if _, ok := registry[name]; ok {
+		panic("backend: Register called twice for " + r.Name)
+	}
+	registry[name] = r
+}
+
+// Lookup returns the registration of the backend name.
+func Lookup(name string) (Registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	r, ok := registry[strings.ToLower(name)]
+	return r, ok
+}
+
+// Registrations returns every registered backend, sorted by name.
+func Registrations() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	rs := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Name < rs[j].Name
+	})
+	return rs
+}
+
+// Names returns the names of the registered backends, sorted.
+func Names() []string {
+	var names []string
+	for _, r := range Registrations() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// Create returns the backend configured by the settings.
+// Missing values take the default of their option.
+func (r Registration) Create(s Settings) (Backend, error) {
+	values := map[string]string{}
+	for k, v := range s.Values {
+		values[k] = v
+	}
+	var missing []string
+	for _, o := range r.Options {
+		v := s.Get(o.Name)
+		if v == "" {
+			v = o.Default
+		}
+		if v == "" && o.Required {
+			missing = append(missing, "TranslationServices."+r.ConfigKey+"."+o.Name)
+		}
+		values[o.Name] = v
+	}
+	if len(missing) > 0 {
+		return nil, errors.New("missing or incomplete configuration file (.t2.yaml): " + strings.Join(missing, ", "))
+	}
+	s.Values = values
+	b, err := r.New(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Name, err)
+	}
+	return b, nil
+}
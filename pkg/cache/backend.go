@@ -0,0 +1,104 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"sync"
+)
+
+// Backend wraps a backend to keep its translations in a store.
+// Only the texts missing from the store are sent to the wrapped backend.
+type Backend struct {
+	backend backend.Backend
+	store   Store
+	// prune prunes the store once, at the first write.
+	prune *sync.Once
+}
+
+// New returns the backend b with a cache.
+func New(b backend.Backend, store Store) Backend {
+	return Backend{backend: b, store: store, prune: &sync.Once{}}
+}
+
+// Unwrap returns the wrapped backend.
+func (c Backend) Unwrap() backend.Backend {
+	return c.backend
+}
+
+func (c Backend) Name() string {
+	return c.backend.Name()
+}
+
+func (c Backend) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	return backend.TranslateOne(ctx, c, text, source, target)
+}
+
+func (c Backend) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	// The name identifies the backend and its options in the keys of the store.
+	name := c.backend.Name()
+	variant, err := backend.Variant(ctx, c.backend, source, target)
+	if err != nil {
+		return nil, err
+	}
+	if variant != "" {
+		name += "\x00" + variant
+	}
+
+	responses := make([]backend.TranslationResponse, len(texts))
+	keys := make([]string, len(texts))
+	var missing []string
+	var missingIndexes []int
+	for i, text := range texts {
+		keys[i] = Key(name, source, target, text)
+		if cached, ok := c.store.Get(keys[i]); ok {
+			responses[i] = backend.TranslationResponse{Text: cached}
+			continue
+		}
+		missing = append(missing, text)
+		missingIndexes = append(missingIndexes, i)
+	}
+	if len(missing) == 0 {
+		return responses, nil
+	}
+
+	translated, err := c.backend.TranslateBatch(ctx, missing, source, target)
+	if err != nil {
+		return nil, err
+	}
+	if len(translated) != len(missing) {
+		return nil, backend.NewMalformedResponseError(c.Name(),
+			fmt.Errorf("%d translations received for %d texts", len(translated), len(missing)))
+	}
+	for j, res := range translated {
+		i := missingIndexes[j]
+		responses[i] = res
+		// The cache is an optimisation: a failure to write it is not an error.
+		_ = c.store.Put(keys[i], res.Text)
+	}
+	c.prune.Do(func() {
+		_ = c.store.Prune()
+	})
+	return responses, nil
+}
+
+func (c Backend) Usage(ctx context.Context) (backend.UsageResponse, error) {
+	return c.backend.Usage(ctx)
+}
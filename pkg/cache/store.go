@@ -0,0 +1,233 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	// keyName matches the files of the entries, named after their keys.
+	keyName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	// subDirName matches the sub directories of the entries.
+	subDirName = regexp.MustCompile(`^[0-9a-f]{2}$`)
+)
+
+// Store is an on-disk cache of translations, with one file per entry.
+type Store struct {
+	Dir string
+	// TTL is the lifetime of an entry, 0 for no expiration.
+	TTL time.Duration
+	// MaxSize is the maximum size of the entries in bytes, 0 for no limit.
+	MaxSize int64
+}
+
+// Stats is the content of a store.
+type Stats struct {
+	Entries int
+	Size    int64
+	Oldest  time.Time
+}
+
+// DefaultDir returns the t2 directory in the user cache directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "t2"), nil
+}
+
+// Key returns the key of the translation of the text by the backend.
+func Key(backend, source, target, text string) string {
+	h := sha256.New()
+	for _, s := range []string{backend, source, target, text} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached text of the key, if any and not expired.
+func (s Store) Get(key string) (string, bool) {
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	if s.expired(info) {
+		_ = os.Remove(path)
+		return "", false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// Put stores the text for the key.
+func (s Store) Put(key, text string) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Prune removes the expired entries, then the oldest ones while the store is above MaxSize.
+func (s Store) Prune() error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].info.ModTime().Before(entries[j].info.ModTime())
+	})
+
+	var size int64
+	var kept []entry
+	for _, e := range entries {
+		if s.expired(e.info) {
+			if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		size += e.info.Size()
+		kept = append(kept, e)
+	}
+	for _, e := range kept {
+		if s.MaxSize <= 0 || size <= s.MaxSize {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		size -= e.info.Size()
+	}
+	return nil
+}
+
+// Stats returns the number of entries, their size and the date of the oldest one.
+func (s Store) Stats() (Stats, error) {
+	entries, err := s.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+	var st Stats
+	for _, e := range entries {
+		st.Entries++
+		st.Size += e.info.Size()
+		if st.Oldest.IsZero() || e.info.ModTime().Before(st.Oldest) {
+			st.Oldest = e.info.ModTime()
+		}
+	}
+	return st, nil
+}
+
+// Clear removes every entry.
+// Only the sub directories of the entries are removed, the directory may be shared.
+func (s Store) Clear() error {
+	dirs, err := s.subDirs()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type entry struct {
+	path string
+	info fs.FileInfo
+}
+
+// entries returns the files of the store.
+func (s Store) entries() ([]entry, error) {
+	dirs, err := s.subDirs()
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for _, dir := range dirs {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || !keyName.MatchString(f.Name()) {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			entries = append(entries, entry{path: filepath.Join(dir, f.Name()), info: info})
+		}
+	}
+	return entries, nil
+}
+
+// subDirs returns the sub directories of the entries, named after the first characters of their keys.
+func (s Store) subDirs() ([]string, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var dirs []string
+	for _, f := range files {
+		if f.IsDir() && subDirName.MatchString(f.Name()) {
+			dirs = append(dirs, filepath.Join(s.Dir, f.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+func (s Store) expired(info fs.FileInfo) bool {
+	return s.TTL > 0 && time.Since(info.ModTime()) > s.TTL
+}
+
+// path returns the file of the key, in a sub directory named after its first characters.
+func (s Store) path(key string) string {
+	return filepath.Join(s.Dir, key[:2], key)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClearKeepsOtherFiles(t *testing.T) {
+	s := Store{Dir: t.TempDir()}
+	if err := s.Put(Key("DeepL", "EN", "FR", "Hello"), "Bonjour"); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(s.Dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("mine"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	otherDir := filepath.Join(s.Dir, "project")
+	if err := os.Mkdir(otherDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get(Key("DeepL", "EN", "FR", "Hello")); ok {
+		t.Error("entry still in the store after Clear")
+	}
+	for _, path := range []string{other, otherDir} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s removed by Clear: %v", path, err)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := Store{Dir: t.TempDir(), TTL: time.Hour, MaxSize: 10}
+	old := Key("DeepL", "EN", "FR", "old")
+	expired := Key("DeepL", "EN", "FR", "expired")
+	recent := Key("DeepL", "EN", "FR", "recent")
+	for _, key := range []string{old, expired, recent} {
+		if err := s.Put(key, "0123456789"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	if err := os.Chtimes(s.path(expired), now, now.Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(s.path(old), now, now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(s.Dir, "notes.txt")
+	if err := os.WriteFile(other, []byte("mine"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Entries != 1 {
+		t.Errorf("got %d entries, want 1", st.Entries)
+	}
+	if _, ok := s.Get(recent); !ok {
+		t.Error("most recent entry removed by Prune")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("%s removed by Prune: %v", other, err)
+	}
+}
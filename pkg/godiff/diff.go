@@ -18,13 +18,50 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package godiff
 
 import (
+	"github.com/rangzen/t2/pkg/t2"
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
 type Diff struct{}
 
-func (Diff) Print(a, b string) string {
+// Compute returns the operations to go from a to b.
+func (Diff) Compute(a, b string) []t2.DiffOperation {
 	dmp := diffmatchpatch.New()
 	d := dmp.DiffMain(a, b, false)
-	return dmp.DiffPrettyText(d)
+	ops := make([]t2.DiffOperation, len(d))
+	for i, op := range d {
+		ops[i] = t2.DiffOperation{Type: diffType(op.Type), Text: op.Text}
+	}
+	return ops
+}
+
+// Print returns the operations as a colored text for the terminal.
+func (Diff) Print(ops []t2.DiffOperation) string {
+	d := make([]diffmatchpatch.Diff, len(ops))
+	for i, op := range ops {
+		d[i] = diffmatchpatch.Diff{Type: dmpOperation(op.Type), Text: op.Text}
+	}
+	return diffmatchpatch.New().DiffPrettyText(d)
+}
+
+func diffType(op diffmatchpatch.Operation) t2.DiffType {
+	switch op {
+	case diffmatchpatch.DiffInsert:
+		return t2.DiffInsert
+	case diffmatchpatch.DiffDelete:
+		return t2.DiffDelete
+	default:
+		return t2.DiffEqual
+	}
+}
+
+func dmpOperation(t t2.DiffType) diffmatchpatch.Operation {
+	switch t {
+	case t2.DiffInsert:
+		return diffmatchpatch.DiffInsert
+	case t2.DiffDelete:
+		return diffmatchpatch.DiffDelete
+	default:
+		return diffmatchpatch.DiffEqual
+	}
 }
@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Block is a part of a Markdown document.
+// Only prose blocks are meant to be translated.
+type Block struct {
+	Text  string
+	Prose bool
+}
+
+var (
+	fence     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	reference = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S+`)
+	indented  = regexp.MustCompile(`^(    |\t)`)
+	// html matches the opening of an HTML block, but not an autolink like <https://example.com>.
+	html = regexp.MustCompile(`^ {0,3}<(?:[A-Za-z][A-Za-z0-9-]*(?:\s|/?>|$)|/[A-Za-z]|!|\?)`)
+)
+
+// Patterns match the inline code, the link targets, the URLs and the HTML tags
+// of the prose blocks.
+var Patterns = []string{
+	"``[^`]*``|`[^`\n]+`",
+	`<[a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]*>`,
+	`\]\([^)\s]*(?:\s+"[^"]*")?\)`,
+	`\]\[[^\]]*\]`,
+	`https?://[^\s<>()]*[^\s<>().,;:!?]`,
+	`</?[a-zA-Z][^>\n]*>`,
+}
+
+// Split splits the document into blocks separated by blank lines.
+// Fenced and indented code blocks, link reference definitions and HTML blocks
+// are not prose.
+func Split(doc string) []Block {
+	lines := strings.Split(strings.ReplaceAll(doc, "\r\n", "\n"), "\n")
+	var blocks []Block
+	var current []string
+	prose := true
+
+	flush := func() {
+		text := strings.Trim(strings.Join(current, "\n"), "\n")
+		if strings.TrimSpace(text) != "" {
+			if prose {
+				text = strings.TrimSpace(text)
+			}
+			blocks = append(blocks, Block{Text: text, Prose: prose})
+		}
+		current = nil
+		prose = true
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			if !prose && len(current) > 0 && indented.MatchString(current[0]) {
+				// Blank lines may belong to an indented code block.
+				current = append(current, line)
+				continue
+			}
+			flush()
+		case fence.MatchString(line):
+			flush()
+			marker := strings.TrimSpace(fence.FindString(line))
+			prose = false
+			current = append(current, line)
+			for i++; i < len(lines); i++ {
+				current = append(current, lines[i])
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), marker) &&
+					strings.Trim(strings.TrimSpace(lines[i]), marker[:1]) == "" {
+					break
+				}
+			}
+			flush()
+		case len(current) == 0 && (indented.MatchString(line) || html.MatchString(line)):
+			prose = false
+			current = append(current, line)
+		case !prose && len(current) > 0 && indented.MatchString(current[0]) && !indented.MatchString(line):
+			flush()
+			i--
+		case reference.MatchString(line):
+			flush()
+			blocks = append(blocks, Block{Text: line})
+		default:
+			current = append(current, line)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// Join reassembles the blocks into a document, using the translated texts in place
+// of the prose blocks, in order.
+func Join(blocks []Block, translated []string) string {
+	parts := make([]string, len(blocks))
+	next := 0
+	for i, b := range blocks {
+		parts[i] = b.Text
+		if b.Prose && next < len(translated) {
+			parts[i] = translated[next]
+			next++
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// Prose returns the texts of the prose blocks.
+func Prose(blocks []Block) []string {
+	var texts []string
+	for _, b := range blocks {
+		if b.Prose {
+			texts = append(texts, b.Text)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package markdown
+
+import "testing"
+
+func TestSplitHTMLBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		prose bool
+	}{
+		{"tag", "<div>\nSome HTML.\n</div>", false},
+		{"closing tag", "</div>", false},
+		{"tag with attributes", `<img src="logo.png">`, false},
+		{"comment", "<!-- a comment -->", false},
+		{"processing instruction", `<?xml version="1.0"?>`, false},
+		{"autolink", "<https://example.com> starts a paragraph.", true},
+		{"email autolink", "<user@example.com> starts a paragraph.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := Split(tt.doc)
+			if len(blocks) != 1 {
+				t.Fatalf("got %d blocks, want 1", len(blocks))
+			}
+			if blocks[0].Prose != tt.prose {
+				t.Errorf("got prose %t, want %t", blocks[0].Prose, tt.prose)
+			}
+		})
+	}
+}
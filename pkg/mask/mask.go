@@ -0,0 +1,125 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package mask
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// placeholder matches the placeholders, even if the translation service added spaces in them.
+var placeholder = regexp.MustCompile(`\{\{\s*T2\s*:\s*(\d+)\s*\}\}`)
+
+// word matches a character of a word, as understood by \b.
+var word = regexp.MustCompile(`\w`)
+
+var (
+	// ErrLostPlaceholder is returned when the translation service dropped a placeholder.
+	ErrLostPlaceholder = errors.New("lost placeholder")
+	// ErrDuplicatedPlaceholder is returned when the translation service repeated a placeholder.
+	ErrDuplicatedPlaceholder = errors.New("duplicated placeholder")
+	// ErrUnknownPlaceholder is returned when the translation service changed the number of a placeholder.
+	ErrUnknownPlaceholder = errors.New("unknown placeholder")
+)
+
+// Masker replaces the spans of text matching its rules with opaque placeholders,
+// so they are not translated, and restores them afterwards.
+type Masker struct {
+	rules *regexp.Regexp
+}
+
+// New returns a Masker for the regular expressions.
+func New(patterns ...string) (Masker, error) {
+	if len(patterns) == 0 {
+		return Masker{}, nil
+	}
+	groups := make([]string, len(patterns))
+	for i, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return Masker{}, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		groups[i] = "(?:" + p + ")"
+	}
+	rules, err := regexp.Compile(strings.Join(groups, "|"))
+	if err != nil {
+		return Masker{}, err
+	}
+	return Masker{rules: rules}, nil
+}
+
+// Mask replaces the matching spans with placeholders.
+// It returns the masked text and the spans to give back to Unmask.
+func (m Masker) Mask(text string) (string, []string) {
+	if m.rules == nil {
+		return text, nil
+	}
+	var spans []string
+	masked := m.rules.ReplaceAllStringFunc(text, func(span string) string {
+		spans = append(spans, span)
+		return "{{T2:" + strconv.Itoa(len(spans)-1) + "}}"
+	})
+	return masked, spans
+}
+
+// Unmask restores the spans in place of the placeholders.
+// Every placeholder must be found exactly once in the text.
+func (m Masker) Unmask(text string, spans []string) (string, error) {
+	if len(spans) == 0 {
+		return text, nil
+	}
+	found := make([]int, len(spans))
+	var err error
+	unmasked := placeholder.ReplaceAllStringFunc(text, func(p string) string {
+		i, convErr := strconv.Atoi(placeholder.FindStringSubmatch(p)[1])
+		if convErr != nil || i >= len(spans) {
+			if err == nil {
+				err = fmt.Errorf("%w %s", ErrUnknownPlaceholder, p)
+			}
+			return p
+		}
+		found[i]++
+		return spans[i]
+	})
+	if err != nil {
+		return "", err
+	}
+	for i, n := range found {
+		switch {
+		case n == 0:
+			return "", fmt.Errorf("%w for %q", ErrLostPlaceholder, spans[i])
+		case n > 1:
+			return "", fmt.Errorf("%w for %q", ErrDuplicatedPlaceholder, spans[i])
+		}
+	}
+	return unmasked, nil
+}
+
+// Term returns the pattern matching the term, which must not be empty, as a whole word.
+func Term(term string) string {
+	pattern := regexp.QuoteMeta(term)
+	if word.MatchString(term[:1]) {
+		pattern = `\b` + pattern
+	}
+	if word.MatchString(term[len(term)-1:]) {
+		pattern += `\b`
+	}
+	return pattern
+}
@@ -0,0 +1,220 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package render
+
+import (
+	"encoding/json"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/t2"
+	"io"
+	"time"
+)
+
+// JSON renders the results as JSON documents.
+// With Lines, each document is written on a single line (NDJSON).
+type JSON struct {
+	Lines bool
+}
+
+type jsonResult struct {
+	Index      int         `json:"index,omitempty"`
+	Original   string      `json:"original"`
+	Clean      bool        `json:"clean"`
+	SourceLang string      `json:"source_lang"`
+	Routes     []jsonRoute `json:"routes"`
+}
+
+type jsonDocument struct {
+	Paragraphs []jsonResult `json:"paragraphs"`
+	Changed    int          `json:"changed"`
+}
+
+type jsonComparison struct {
+	Original   string      `json:"original"`
+	SourceLang string      `json:"source_lang"`
+	Route      []string    `json:"route"`
+	Results    []jsonRoute `json:"results"`
+	Pairs      []jsonPair  `json:"pairs"`
+}
+
+type jsonRoute struct {
+	Route            []string  `json:"route"`
+	Hops             []jsonHop `json:"hops"`
+	DoubleTranslated string    `json:"double_translated"`
+	Diff             []jsonOp  `json:"diff"`
+	Distance         int       `json:"distance"`
+	Similarity       float64   `json:"similarity"`
+	DurationMs       int64     `json:"duration_ms"`
+}
+
+type jsonHop struct {
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	Backend    string `json:"backend"`
+	Text       string `json:"text"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type jsonOp struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type jsonPair struct {
+	A          string   `json:"a"`
+	B          string   `json:"b"`
+	Same       bool     `json:"same"`
+	Diff       []jsonOp `json:"diff"`
+	Similarity float64  `json:"similarity"`
+}
+
+type jsonUsage struct {
+	Backend string `json:"backend"`
+	Used    int64  `json:"used"`
+	Limit   int64  `json:"limit"`
+}
+
+type jsonLanguages struct {
+	Backend   string         `json:"backend"`
+	Languages []jsonLanguage `json:"languages"`
+}
+
+type jsonLanguage struct {
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
+// Translation writes the result as one JSON document.
+func (r JSON) Translation(w io.Writer, res t2.Result) error {
+	return r.encode(w, newJSONResult(res))
+}
+
+// Document writes the document as one JSON document.
+// With Lines, each paragraph is written as its own document.
+func (r JSON) Document(w io.Writer, doc t2.Document) error {
+	jd := jsonDocument{
+		Paragraphs: make([]jsonResult, len(doc.Paragraphs)),
+		Changed:    doc.Changed(),
+	}
+	for i, p := range doc.Paragraphs {
+		jd.Paragraphs[i] = newJSONResult(p)
+		jd.Paragraphs[i].Index = i + 1
+		if r.Lines {
+			if err := r.encode(w, jd.Paragraphs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Lines {
+		return nil
+	}
+	return r.encode(w, jd)
+}
+
+// Comparison writes the comparison as one JSON document.
+func (r JSON) Comparison(w io.Writer, c t2.Comparison) error {
+	jc := jsonComparison{
+		Original:   c.Original,
+		SourceLang: c.SourceLang,
+		Route:      c.Route,
+		Results:    make([]jsonRoute, len(c.Results)),
+		Pairs:      make([]jsonPair, len(c.Pairs)),
+	}
+	for i, rr := range c.Results {
+		jc.Results[i] = newJSONRoute(rr)
+	}
+	for i, p := range c.Pairs {
+		jc.Pairs[i] = jsonPair{
+			A:          p.A,
+			B:          p.B,
+			Same:       p.Same(),
+			Diff:       newJSONDiff(p.Diff),
+			Similarity: t2.Similarity(p.Diff),
+		}
+	}
+	return r.encode(w, jc)
+}
+
+// Usage writes the usage of the backend as one JSON document.
+func (r JSON) Usage(w io.Writer, service string, u backend.UsageResponse) error {
+	return r.encode(w, jsonUsage{Backend: service, Used: u.Used, Limit: u.Limit})
+}
+
+// Languages writes the supported languages of the backend as one JSON document.
+func (r JSON) Languages(w io.Writer, service string, languages []backend.Language) error {
+	jl := jsonLanguages{Backend: service, Languages: make([]jsonLanguage, len(languages))}
+	for i, l := range languages {
+		jl.Languages[i] = jsonLanguage{Code: l.Code, Name: l.Name}
+	}
+	return r.encode(w, jl)
+}
+
+func (r JSON) encode(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	if !r.Lines {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+func newJSONResult(res t2.Result) jsonResult {
+	jr := jsonResult{
+		Original:   res.Original,
+		Clean:      res.Clean(),
+		SourceLang: res.SourceLang,
+		Routes:     make([]jsonRoute, len(res.Routes)),
+	}
+	for i, rr := range res.Routes {
+		jr.Routes[i] = newJSONRoute(rr)
+	}
+	return jr
+}
+
+func newJSONRoute(rr t2.RouteResult) jsonRoute {
+	jr := jsonRoute{
+		Route:            rr.Route,
+		Hops:             make([]jsonHop, len(rr.Hops)),
+		DoubleTranslated: rr.Final(),
+		Diff:             newJSONDiff(rr.Diff),
+		Distance:         rr.Distance,
+		Similarity:       t2.Similarity(rr.Diff),
+		DurationMs:       milliseconds(rr.Duration),
+	}
+	for i, h := range rr.Hops {
+		jr.Hops[i] = jsonHop{
+			Source:     h.Source,
+			Target:     h.Target,
+			Backend:    h.Backend,
+			Text:       h.Text,
+			DurationMs: milliseconds(h.Duration),
+		}
+	}
+	return jr
+}
+
+func newJSONDiff(ops []t2.DiffOperation) []jsonOp {
+	jo := make([]jsonOp, len(ops))
+	for i, op := range ops {
+		jo[i] = jsonOp{Type: string(op.Type), Text: op.Text}
+	}
+	return jo
+}
+
+func milliseconds(d time.Duration) int64 {
+	return d.Milliseconds()
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package render
+
+import (
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/t2"
+	"io"
+)
+
+// Renderer is the interface that wraps the output of the commands.
+type Renderer interface {
+	Translation(w io.Writer, res t2.Result) error
+	Comparison(w io.Writer, c t2.Comparison) error
+	Document(w io.Writer, doc t2.Document) error
+	Usage(w io.Writer, service string, u backend.UsageResponse) error
+	Languages(w io.Writer, service string, languages []backend.Language) error
+}
+
+// Formats are the names of the available output formats.
+var Formats = []string{"text", "json", "ndjson"}
+
+// New returns the renderer for the output format.
+func New(format string, diff t2.Diff, diffOnly bool) (Renderer, error) {
+	switch format {
+	case "text":
+		return Text{Diff: diff, DiffOnly: diffOnly}, nil
+	case "json":
+		return JSON{}, nil
+	case "ndjson":
+		return JSON{Lines: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package render
+
+import (
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/t2"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Text renders the results as titled sections for the terminal.
+// With DiffOnly, only the diff versions are rendered.
+type Text struct {
+	Diff     t2.Diff
+	DiffOnly bool
+}
+
+// Translation writes the original text, every intermediate text and the diff of each route,
+// followed by a summary when several routes are used.
+func (r Text) Translation(w io.Writer, res t2.Result) error {
+	var sb strings.Builder
+	r.writeTranslation(&sb, res)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Document writes a report for each paragraph, followed by a summary.
+// Paragraphs that came back identical are marked clean and only their original text is written.
+func (r Text) Document(w io.Writer, doc t2.Document) error {
+	var sb strings.Builder
+	for i, p := range doc.Paragraphs {
+		if r.DiffOnly {
+			if !p.Clean() {
+				fmt.Fprintf(&sb, "## Paragraph %d\n", i+1)
+				r.writeTranslation(&sb, p)
+			}
+			continue
+		}
+		if p.Clean() {
+			fmt.Fprintf(&sb, "## Paragraph %d (clean)\n", i+1)
+			sb.WriteString(p.Original + "\n")
+			continue
+		}
+		fmt.Fprintf(&sb, "## Paragraph %d (changed)\n", i+1)
+		r.writeTranslation(&sb, p)
+	}
+	fmt.Fprintf(&sb, "# Summary\n%d paragraphs, %d clean, %d changed\n",
+		len(doc.Paragraphs), len(doc.Paragraphs)-doc.Changed(), doc.Changed())
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeTranslation writes the sections of a result.
+func (r Text) writeTranslation(sb *strings.Builder, res t2.Result) {
+	if !r.DiffOnly {
+		sb.WriteString("# Original text\n")
+		sb.WriteString(res.Original + "\n")
+	}
+	for _, rr := range res.Routes {
+		if !r.DiffOnly {
+			last := len(rr.Hops) - 1
+			for i, h := range rr.Hops {
+				title := "Pivot text"
+				if i == last {
+					title = "Double translated text"
+				}
+				fmt.Fprintf(sb, "# %s (%s -> %s by %s)\n", title, h.Source, h.Target, h.Backend)
+				sb.WriteString(h.Text + "\n")
+			}
+			sb.WriteString("# Diff version\n")
+		} else if len(res.Routes) > 1 {
+			fmt.Fprintf(sb, "[%s] ", rr.Route)
+		}
+		sb.WriteString(r.Diff.Print(rr.Diff) + "\n")
+	}
+	if len(res.Routes) > 1 {
+		writeSummary(sb, res)
+	}
+}
+
+// Comparison writes the double translated text of each backend with its diff,
+// then the diff between the results of each pair of backends.
+func (r Text) Comparison(w io.Writer, c t2.Comparison) error {
+	var sb strings.Builder
+	if !r.DiffOnly {
+		sb.WriteString("# Original text\n")
+		sb.WriteString(c.Original + "\n")
+	}
+	for i, rr := range c.Results {
+		if !r.DiffOnly {
+			fmt.Fprintf(&sb, "# Double translated text (%s by %s)\n", c.SourceLang, c.Backends[i])
+			sb.WriteString(rr.Final() + "\n")
+			sb.WriteString("# Diff version\n")
+		} else {
+			fmt.Fprintf(&sb, "[%s] ", c.Backends[i])
+		}
+		sb.WriteString(r.Diff.Print(rr.Diff) + "\n")
+	}
+	for _, p := range c.Pairs {
+		if !r.DiffOnly {
+			fmt.Fprintf(&sb, "# Diff between %s and %s\n", p.A, p.B)
+		} else {
+			fmt.Fprintf(&sb, "[%s/%s] ", p.A, p.B)
+		}
+		if p.Same() {
+			sb.WriteString("Same result.\n")
+			continue
+		}
+		sb.WriteString(r.Diff.Print(p.Diff) + "\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Usage writes the used and limit counts of the backend.
+func (r Text) Usage(w io.Writer, _ string, u backend.UsageResponse) error {
+	_, err := fmt.Fprintf(w, "Usage: %d/%d\n", u.Used, u.Limit)
+	return err
+}
+
+// Languages writes one supported language of the backend per line.
+func (r Text) Languages(w io.Writer, _ string, languages []backend.Language) error {
+	for _, l := range languages {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", l.Code, l.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSummary writes the routes sorted from the most to the least changed text.
+func writeSummary(sb *strings.Builder, res t2.Result) {
+	sorted := make([]t2.RouteResult, len(res.Routes))
+	copy(sorted, res.Routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Distance > sorted[j].Distance
+	})
+
+	sb.WriteString("# Summary\n")
+	for _, rr := range sorted {
+		fmt.Fprintf(sb, "%s: %d changes\n", rr.Route, rr.Distance)
+	}
+	fmt.Fprintf(sb, "Most changed: %s\n", res.MostChanged().Route)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/rangzen/t2/pkg/backend"
+	"math/rand"
+	"time"
+)
+
+// Policy is the configuration of the retries.
+type Policy struct {
+	// MaxAttempts is the maximum number of calls, including the first one.
+	MaxAttempts int
+	// InitialDelay is the delay before the first retry, doubled at each retry.
+	InitialDelay time.Duration
+	// MaxDelay is the maximum delay between two calls.
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is the policy used when nothing is configured.
+var DefaultPolicy = Policy{
+	MaxAttempts:  4,
+	InitialDelay: 500 * time.Millisecond,
+	MaxDelay:     30 * time.Second,
+}
+
+// Backend wraps a backend to retry the calls failing with a retryable error,
+// like a rate limit or a server error, with an exponential backoff.
+type Backend struct {
+	backend backend.Backend
+	policy  Policy
+}
+
+// New returns the backend b with retries.
+func New(b backend.Backend, policy Policy) Backend {
+	return Backend{backend: b, policy: policy}
+}
+
+// Unwrap returns the wrapped backend.
+func (r Backend) Unwrap() backend.Backend {
+	return r.backend
+}
+
+func (r Backend) Name() string {
+	return r.backend.Name()
+}
+
+func (r Backend) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	var res backend.TranslationResponse
+	err := r.do(ctx, func() error {
+		var err error
+		res, err = r.backend.Translate(ctx, text, source, target)
+		return err
+	})
+	return res, err
+}
+
+// TranslateBatch sends again only the texts that are not translated yet:
+// the translations returned with an error, like those of the first chunks
+// of a batch, are kept.
+func (r Backend) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	res := make([]backend.TranslationResponse, 0, len(texts))
+	err := r.do(ctx, func() error {
+		done, err := r.backend.TranslateBatch(ctx, texts[len(res):], source, target)
+		if err != nil && len(done) > len(texts)-len(res) {
+			return err
+		}
+		res = append(res, done...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r Backend) Usage(ctx context.Context) (backend.UsageResponse, error) {
+	var res backend.UsageResponse
+	err := r.do(ctx, func() error {
+		var err error
+		res, err = r.backend.Usage(ctx)
+		return err
+	})
+	return res, err
+}
+
+// do calls f until it succeeds, fails with a non retryable error,
+// the maximum number of attempts is reached or the context is done.
+func (r Backend) do(ctx context.Context, f func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = f()
+		if err == nil || !backend.Retryable(err) || attempt+1 >= r.policy.MaxAttempts {
+			return err
+		}
+		delay, ok := r.delay(attempt, err)
+		if !ok {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the time to wait before the next attempt.
+// The Retry-After delay asked by the service is honoured if it is not above MaxDelay,
+// otherwise there is no retry.
+func (r Backend) delay(attempt int, err error) (time.Duration, bool) {
+	var berr *backend.Error
+	if errors.As(err, &berr) && berr.RetryAfter > 0 {
+		return berr.RetryAfter, berr.RetryAfter <= r.policy.MaxDelay
+	}
+
+	backoff := r.policy.InitialDelay << attempt
+	if backoff <= 0 || backoff > r.policy.MaxDelay {
+		backoff = r.policy.MaxDelay
+	}
+	// Equal jitter: between half and the full backoff.
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)), true
+}
@@ -0,0 +1,207 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/rangzen/t2/pkg/backend"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scripted is a backend sending the texts by requests of 2 texts.
+// The requests fail with the errors of the script, in order, then succeed.
+type scripted struct {
+	errs []error
+	// requests are the texts of the requests received.
+	requests [][]string
+}
+
+func (s *scripted) Name() string {
+	return "Scripted"
+}
+
+func (s *scripted) Translate(ctx context.Context, text string, source string, target string) (backend.TranslationResponse, error) {
+	return backend.TranslateOne(ctx, s, text, source, target)
+}
+
+func (s *scripted) TranslateBatch(_ context.Context, texts []string, _ string, _ string) ([]backend.TranslationResponse, error) {
+	return backend.Chunk(texts, 2, func(chunk []string) ([]backend.TranslationResponse, error) {
+		s.requests = append(s.requests, chunk)
+		if len(s.errs) > 0 {
+			err := s.errs[0]
+			s.errs = s.errs[1:]
+			if err != nil {
+				return nil, err
+			}
+		}
+		responses := make([]backend.TranslationResponse, len(chunk))
+		for i, text := range chunk {
+			responses[i].Text = strings.ToUpper(text)
+		}
+		return responses, nil
+	})
+}
+
+func (s *scripted) Usage(context.Context) (backend.UsageResponse, error) {
+	return backend.UsageResponse{}, nil
+}
+
+var fast = Policy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+func kind(k error) error {
+	return &backend.Error{Backend: "Scripted", Kind: k}
+}
+
+func TestTranslateBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		texts    []string
+		requests [][]string
+		wantErr  error
+	}{
+		{
+			name:     "rate limited once",
+			errs:     []error{kind(backend.ErrRateLimited)},
+			texts:    []string{"a"},
+			requests: [][]string{{"a"}, {"a"}},
+		},
+		{
+			name:     "server error on every attempt",
+			errs:     []error{kind(backend.ErrServer), kind(backend.ErrServer), kind(backend.ErrServer)},
+			texts:    []string{"a"},
+			requests: [][]string{{"a"}, {"a"}, {"a"}},
+			wantErr:  backend.ErrServer,
+		},
+		{
+			name:     "authentication",
+			errs:     []error{kind(backend.ErrAuth)},
+			texts:    []string{"a"},
+			requests: [][]string{{"a"}},
+			wantErr:  backend.ErrAuth,
+		},
+		{
+			name:     "quota exceeded",
+			errs:     []error{kind(backend.ErrQuotaExceeded)},
+			texts:    []string{"a"},
+			requests: [][]string{{"a"}},
+			wantErr:  backend.ErrQuotaExceeded,
+		},
+		{
+			name:     "invalid request",
+			errs:     []error{kind(backend.ErrRequest)},
+			texts:    []string{"a"},
+			requests: [][]string{{"a"}},
+			wantErr:  backend.ErrRequest,
+		},
+		{
+			name:     "Retry-After above MaxDelay",
+			errs:     []error{&backend.Error{Backend: "Scripted", Kind: backend.ErrRateLimited, RetryAfter: time.Minute}},
+			texts:    []string{"a"},
+			requests: [][]string{{"a"}},
+			wantErr:  backend.ErrRateLimited,
+		},
+		{
+			name:     "failure of a later request",
+			errs:     []error{nil, kind(backend.ErrServer)},
+			texts:    []string{"a", "b", "c", "d", "e"},
+			requests: [][]string{{"a", "b"}, {"c", "d"}, {"c", "d"}, {"e"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &scripted{errs: tt.errs}
+			res, err := New(s, fast).TranslateBatch(context.Background(), tt.texts, "EN", "FR")
+			if !reflect.DeepEqual(s.requests, tt.requests) {
+				t.Errorf("got requests %q, want %q", s.requests, tt.requests)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, r := range res {
+				if r.Text != strings.ToUpper(tt.texts[i]) {
+					t.Errorf("translation %d: got %q", i, r.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestCancelDuringWait(t *testing.T) {
+	s := &scripted{errs: []error{kind(backend.ErrServer)}}
+	policy := Policy{MaxAttempts: 2, InitialDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := New(s, policy).Translate(ctx, "a", "EN", "FR")
+	if err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if len(s.requests) != 1 {
+		t.Errorf("got %d requests, want 1", len(s.requests))
+	}
+}
+
+func TestDelay(t *testing.T) {
+	r := New(nil, Policy{MaxAttempts: 10, InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second})
+	tests := []struct {
+		name     string
+		attempt  int
+		err      error
+		min, max time.Duration
+		retry    bool
+	}{
+		{name: "first retry", attempt: 0, err: kind(backend.ErrServer), min: 50 * time.Millisecond, max: 100 * time.Millisecond, retry: true},
+		{name: "doubled", attempt: 2, err: kind(backend.ErrServer), min: 200 * time.Millisecond, max: 400 * time.Millisecond, retry: true},
+		{name: "capped", attempt: 5, err: kind(backend.ErrServer), min: 500 * time.Millisecond, max: time.Second, retry: true},
+		{name: "overflow", attempt: 70, err: kind(backend.ErrServer), min: 500 * time.Millisecond, max: time.Second, retry: true},
+		{
+			name:  "Retry-After",
+			err:   &backend.Error{Kind: backend.ErrRateLimited, RetryAfter: 3 * time.Second / 4},
+			min:   3 * time.Second / 4,
+			max:   3 * time.Second / 4,
+			retry: true,
+		},
+		{
+			name:  "Retry-After above MaxDelay",
+			err:   &backend.Error{Kind: backend.ErrRateLimited, RetryAfter: 2 * time.Second},
+			min:   2 * time.Second,
+			max:   2 * time.Second,
+			retry: false,
+		},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			d, retry := r.delay(tt.attempt, tt.err)
+			if retry != tt.retry || d < tt.min || d > tt.max {
+				t.Errorf("%s: got %v (retry %v), want between %v and %v (retry %v)", tt.name, d, retry, tt.min, tt.max, tt.retry)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2
+
+// The built-in backends register themselves in the backend package.
+import (
+	_ "github.com/rangzen/t2/pkg/backend/azure"
+	_ "github.com/rangzen/t2/pkg/backend/deepl"
+	_ "github.com/rangzen/t2/pkg/backend/google"
+	_ "github.com/rangzen/t2/pkg/backend/libretranslate"
+	_ "github.com/rangzen/t2/pkg/backend/mock"
+	_ "github.com/rangzen/t2/pkg/backend/openai"
+	_ "github.com/rangzen/t2/pkg/backend/plugin"
+)
@@ -0,0 +1,80 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Compare runs the round trip through each backend in parallel.
+// The comparison holds the result of each backend with its diff,
+// and the diff between the results of each pair of backends:
+// when several backends agree on a correction, it is likely a real mistake.
+// If one of the backends fails, the others are cancelled.
+// The forward and back backends of t are ignored.
+func (t T2) Compare(ctx context.Context, text string, backends []Backend) (Comparison, error) {
+	if len(t.config.Routes) != 1 {
+		return Comparison{}, errors.New("compare needs exactly one pivot language or route")
+	}
+	if len(backends) < 2 {
+		return Comparison{}, errors.New("compare needs at least two configured translation services")
+	}
+
+	c := Comparison{
+		Original:   text,
+		SourceLang: t.config.SourceLang,
+		Route:      t.config.Routes[0],
+		Backends:   make([]string, len(backends)),
+		Results:    make([]RouteResult, len(backends)),
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(backends))
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		c.Backends[i] = b.Name()
+		wg.Add(1)
+		go func(i int, b Backend) {
+			defer wg.Done()
+			single := NewT2(t.config, b, b, t.diff, t.clipboard).WithProtector(t.protector)
+			c.Results[i], errs[i] = single.roundTrip(ctx, text, c.Route)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, b)
+	}
+	wg.Wait()
+	if i, err := firstError(errs); err != nil {
+		return Comparison{}, fmt.Errorf("%s: %w", c.Backends[i], err)
+	}
+
+	for i := 0; i < len(c.Results); i++ {
+		for j := i + 1; j < len(c.Results); j++ {
+			c.Pairs = append(c.Pairs, PairDiff{
+				A:    c.Backends[i],
+				B:    c.Backends[j],
+				Diff: t.diff.Compute(c.Results[i].Final(), c.Results[j].Final()),
+			})
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2_test
+
+import (
+	"context"
+	"github.com/rangzen/t2/pkg/backend/mock"
+	"github.com/rangzen/t2/pkg/godiff"
+	"github.com/rangzen/t2/pkg/mask"
+	"github.com/rangzen/t2/pkg/t2"
+	"testing"
+)
+
+func TestCompareProtectsTerms(t *testing.T) {
+	rewriter := newMock(t, mock.Fixture{Rules: []mock.Rule{
+		{Target: "FR", Pattern: "FooCloud", Replace: "NuageFoo"},
+		{Target: "EN-US", Pattern: "NuageFoo", Replace: "CloudFoo"},
+	}})
+	echo := newMock(t, mock.Fixture{})
+	config := t2.Config{SourceLang: "EN-US", Routes: []t2.Route{{"FR"}}}
+	m, err := mask.New(mask.Term("FooCloud"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := t2.NewT2(config, nil, nil, godiff.Diff{}, nil).WithProtector(m)
+
+	c, err := svc.Compare(context.Background(), "I use FooCloud", []t2.Backend{
+		named(rewriter, "A"),
+		named(echo, "B"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rr := range c.Results {
+		if got := rr.Final(); got != "I use FooCloud" {
+			t.Errorf("%s: got %q, want the protected term untouched", c.Backends[i], got)
+		}
+	}
+	if !c.Pairs[0].Same() {
+		t.Errorf("got a diff between %s and %s, want none", c.Pairs[0].A, c.Pairs[0].B)
+	}
+}
+
+func newMock(t *testing.T, f mock.Fixture) mock.TranslationService {
+	t.Helper()
+	m, err := mock.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// named returns the mock displayed with another name.
+func named(m mock.TranslationService, name string) mock.TranslationService {
+	m.DisplayName = name
+	return m
+}
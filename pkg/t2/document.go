@@ -0,0 +1,88 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const defaultBatchSize = 50
+
+var blankLines = regexp.MustCompile(`\n[ \t]*\n`)
+
+// SplitParagraphs splits the text on blank lines.
+// Empty paragraphs are dropped.
+func SplitParagraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var paragraphs []string
+	for _, p := range blankLines.Split(text, -1) {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// TranslateDocument runs the double translation of each paragraph.
+// Paragraphs are sent by batches of BatchSize, to limit the number of requests
+// while respecting the limits of the backends.
+// If the copyToClipboard flag is set, it also copies the double translated paragraphs
+// of the first route to the clipboard.
+func (t T2) TranslateDocument(ctx context.Context, paragraphs []string) (Document, error) {
+	batchSize := t.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	doc := Document{Paragraphs: make([]Result, len(paragraphs))}
+	for start := 0; start < len(paragraphs); start += batchSize {
+		end := start + batchSize
+		if end > len(paragraphs) {
+			end = len(paragraphs)
+		}
+		if err := t.translateBatch(ctx, paragraphs[start:end], doc.Paragraphs[start:end]); err != nil {
+			return Document{}, fmt.Errorf("paragraphs %d to %d: %w", start+1, end, err)
+		}
+	}
+
+	if t.config.CopyToClipboard {
+		finals := make([]string, len(doc.Paragraphs))
+		for i, p := range doc.Paragraphs {
+			finals[i] = p.Routes[0].Final()
+		}
+		if err := t.clipboard.Write(strings.Join(finals, "\n\n")); err != nil {
+			return Document{}, err
+		}
+	}
+
+	return doc, nil
+}
+
+// translateBatch translates the paragraphs in a single batch into results.
+func (t T2) translateBatch(ctx context.Context, paragraphs []string, results []Result) error {
+	translated, err := t.translateAll(ctx, paragraphs)
+	if err != nil {
+		return err
+	}
+	copy(results, translated)
+	return nil
+}
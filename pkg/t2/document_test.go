@@ -0,0 +1,77 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2_test
+
+import (
+	"context"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/godiff"
+	"github.com/rangzen/t2/pkg/t2"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recorder records the size of the batches sent to the backend.
+type recorder struct {
+	backend.Backend
+	mu      *sync.Mutex
+	batches *[]int
+}
+
+func (r recorder) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	r.mu.Lock()
+	*r.batches = append(*r.batches, len(texts))
+	r.mu.Unlock()
+	return r.Backend.TranslateBatch(ctx, texts, source, target)
+}
+
+func TestTranslateDocumentBatches(t *testing.T) {
+	paragraphs := []string{"one", "two", "three", "four", "five"}
+	tests := []struct {
+		batchSize int
+		// want are the sizes of the batches, two hops per batch.
+		want []int
+	}{
+		{batchSize: 0, want: []int{5, 5}},
+		{batchSize: 1, want: []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{batchSize: 2, want: []int{2, 2, 2, 2, 1, 1}},
+		{batchSize: 5, want: []int{5, 5}},
+	}
+	for _, tt := range tests {
+		var batches []int
+		r := recorder{Backend: newTagger(t), mu: &sync.Mutex{}, batches: &batches}
+		config := t2.Config{SourceLang: "EN-US", Routes: []t2.Route{{"FR"}}, BatchSize: tt.batchSize}
+		svc := t2.NewT2(config, r, r, godiff.Diff{}, nil)
+
+		doc, err := svc.TranslateDocument(context.Background(), paragraphs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(batches, tt.want) {
+			t.Errorf("batch size %d: got batches %v, want %v", tt.batchSize, batches, tt.want)
+		}
+		for i, p := range doc.Paragraphs {
+			want := paragraphs[i] + "/FR/EN-US"
+			if p.Original != paragraphs[i] || p.Routes[0].Final() != want {
+				t.Errorf("batch size %d: paragraph %d is %q -> %q, want %q -> %q",
+					tt.batchSize, i, p.Original, p.Routes[0].Final(), paragraphs[i], want)
+			}
+		}
+	}
+}
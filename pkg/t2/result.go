@@ -0,0 +1,181 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2
+
+import "time"
+
+// DiffType is the kind of a diff operation.
+type DiffType string
+
+const (
+	DiffEqual  DiffType = "equal"
+	DiffInsert DiffType = "insert"
+	DiffDelete DiffType = "delete"
+)
+
+// DiffOperation is one step to go from the original text to the double translated text.
+type DiffOperation struct {
+	Type DiffType
+	Text string
+}
+
+// Result is the outcome of the double translation of a text.
+type Result struct {
+	Original   string
+	SourceLang string
+	Routes     []RouteResult
+}
+
+// RouteResult is the outcome of a round trip through one route.
+type RouteResult struct {
+	Route    Route
+	Hops     []HopResult
+	Diff     []DiffOperation
+	Distance int
+	Duration time.Duration
+}
+
+// HopResult is the text produced by one hop of a route.
+type HopResult struct {
+	Source   string
+	Target   string
+	Backend  string
+	Text     string
+	Duration time.Duration
+}
+
+// Comparison is the outcome of the same round trip through several backends.
+type Comparison struct {
+	Original   string
+	SourceLang string
+	Route      Route
+	Backends   []string
+	Results    []RouteResult
+	Pairs      []PairDiff
+}
+
+// PairDiff is the difference between the double translated texts of two backends.
+type PairDiff struct {
+	A    string
+	B    string
+	Diff []DiffOperation
+}
+
+// Document is the outcome of the double translation of a text, paragraph by paragraph.
+type Document struct {
+	Paragraphs []Result
+}
+
+// Final returns the text translated back to the source language.
+func (r RouteResult) Final() string {
+	if len(r.Hops) == 0 {
+		return ""
+	}
+	return r.Hops[len(r.Hops)-1].Text
+}
+
+// MostChanged returns the route whose double translated text is the most different
+// from the original text.
+func (r Result) MostChanged() RouteResult {
+	var most RouteResult
+	for i, rr := range r.Routes {
+		if i == 0 || rr.Distance > most.Distance {
+			most = rr
+		}
+	}
+	return most
+}
+
+// Clean reports whether every route gave back the original text.
+func (r Result) Clean() bool {
+	for _, rr := range r.Routes {
+		if rr.Final() != r.Original {
+			return false
+		}
+	}
+	return true
+}
+
+// Changed returns the number of paragraphs that did not come back identical.
+func (d Document) Changed() int {
+	changed := 0
+	for _, p := range d.Paragraphs {
+		if !p.Clean() {
+			changed++
+		}
+	}
+	return changed
+}
+
+// Same reports whether both backends returned the same double translated text.
+func (p PairDiff) Same() bool {
+	for _, op := range p.Diff {
+		if op.Type != DiffEqual {
+			return false
+		}
+	}
+	return true
+}
+
+// Distance returns the Levenshtein distance, in characters, described by the diff operations.
+func Distance(ops []DiffOperation) int {
+	distance, insertions, deletions := 0, 0, 0
+	for _, op := range ops {
+		n := len([]rune(op.Text))
+		switch op.Type {
+		case DiffInsert:
+			insertions += n
+		case DiffDelete:
+			deletions += n
+		case DiffEqual:
+			distance += max(insertions, deletions)
+			insertions, deletions = 0, 0
+		}
+	}
+	return distance + max(insertions, deletions)
+}
+
+// Similarity returns a score between 0 and 1 of how close the two texts described
+// by the diff operations are, 1 meaning identical.
+func Similarity(ops []DiffOperation) float64 {
+	a, b := 0, 0
+	for _, op := range ops {
+		n := len([]rune(op.Text))
+		switch op.Type {
+		case DiffInsert:
+			b += n
+		case DiffDelete:
+			a += n
+		case DiffEqual:
+			a += n
+			b += n
+		}
+	}
+	longest := max(a, b)
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(Distance(ops))/float64(longest)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2
+
+import "strings"
+
+// Hop is one translation step of a route.
+type Hop struct {
+	Source string
+	Target string
+}
+
+// Route is the chain of pivot languages walked from the source language
+// before going back to the source language.
+type Route []string
+
+// Hops returns the translation steps of the route,
+// starting from and ending with the source language.
+func (r Route) Hops(source string) []Hop {
+	hops := make([]Hop, 0, len(r)+1)
+	from := source
+	for _, pivot := range r {
+		hops = append(hops, Hop{Source: from, Target: pivot})
+		from = pivot
+	}
+	return append(hops, Hop{Source: from, Target: source})
+}
+
+// String returns the pivot languages of the route, e.g. "FR -> DE".
+func (r Route) String() string {
+	return strings.Join(r, " -> ")
+}
+
+// PivotRoutes returns one single hop route for each pivot language.
+func PivotRoutes(pivots []string) []Route {
+	routes := make([]Route, len(pivots))
+	for i, pivot := range pivots {
+		routes[i] = Route{pivot}
+	}
+	return routes
+}
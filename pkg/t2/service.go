@@ -18,32 +18,39 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package t2
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/rangzen/t2/pkg/backend"
-	"github.com/rangzen/t2/pkg/backend/deepl"
-	"github.com/rangzen/t2/pkg/backend/google"
+	"sync"
+	"time"
 )
 
 // Backend is the interface that wraps the translation backend methods.
-type Backend interface {
-	Name() string
-	Translate(text string, source string, pivot string) (backend.TranslationResponse, error)
-	Usage() (backend.UsageResponse, error)
-}
+type Backend = backend.Backend
 
 // Config is the configuration of the package.
 type Config struct {
 	SourceLang      string
-	PivotLang       string
-	DiffOnly        bool
+	Routes          []Route
 	CopyToClipboard bool
+	// BatchSize is the number of paragraphs of a document sent in a single batch.
+	BatchSize int
 }
 
-// Diff is the interface that wraps the pretty print of the difference between
-// the original text and the double translated text.
+// Diff is the interface that wraps the computation and the pretty print
+// of the difference between the original text and the double translated text.
 type Diff interface {
-	Print(a, b string) string
+	Compute(a, b string) []DiffOperation
+	Print(ops []DiffOperation) string
+}
+
+// Protector is the interface that wraps the masking of the spans of text
+// that must not be translated, like code or URLs.
+// Unmask fails if the translation service lost or duplicated a masked span.
+type Protector interface {
+	Mask(text string) (string, []string)
+	Unmask(text string, spans []string) (string, error)
 }
 
 // Clipboard is the interface that wraps the copy to clipboard functionality.
@@ -53,85 +60,221 @@ type Clipboard interface {
 }
 
 // T2 is the main struct of the package.
+// The forward backend translates towards the pivot languages,
+// the back backend translates the last hop back to the source language.
 type T2 struct {
 	config    Config
-	backend   Backend
+	forward   Backend
+	back      Backend
 	diff      Diff
 	clipboard Clipboard
+	protector Protector
 }
 
 // NewT2 returns a new T2 struct.
-func NewT2(config Config, backend Backend, diff Diff, clipboard Clipboard) T2 {
+// Use the same backend for forward and back to translate with a single service.
+func NewT2(config Config, forward, back Backend, diff Diff, clipboard Clipboard) T2 {
 	return T2{
 		config:    config,
-		backend:   backend,
+		forward:   forward,
+		back:      back,
 		diff:      diff,
 		clipboard: clipboard,
 	}
 }
 
+// WithProtector returns a copy of t that masks the spans of text found by p
+// before each hop and restores them after.
+func (t T2) WithProtector(p Protector) T2 {
+	t.protector = p
+	return t
+}
+
 // Translate is the main function of the package.
-// It translates the text from the source language to the pivot language,
-// then back to the source language.
-// It then prints the diff between the original text and the double translated text.
-// If the copyToClipboard flag is set, it also copies the double translated text to the clipboard.
-func (t T2) Translate(text string) error {
-	if !t.config.DiffOnly {
-		fmt.Println("# Original text")
-		fmt.Println(text)
+// It translates the text from the source language through the languages
+// of each route, then back to the source language. Routes are processed concurrently.
+// The result holds every intermediate text and, for each route, the diff between
+// the original text and the double translated text.
+// If one of the requests fails, the others are cancelled.
+// If the copyToClipboard flag is set, it also copies the double translated text
+// of the first route to the clipboard.
+func (t T2) Translate(ctx context.Context, text string) (Result, error) {
+	result, err := t.translate(ctx, text)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if t.config.CopyToClipboard {
+		if err := t.clipboard.Write(result.Routes[0].Final()); err != nil {
+			return Result{}, err
+		}
 	}
 
-	firstPass, err := t.backend.Translate(text, t.config.SourceLang, t.config.PivotLang)
+	return result, nil
+}
+
+// translate runs the round trip of every route in parallel.
+func (t T2) translate(ctx context.Context, text string) (Result, error) {
+	results, err := t.translateAll(ctx, []string{text})
 	if err != nil {
-		return err
+		return Result{}, err
+	}
+	return results[0], nil
+}
+
+// translateAll runs the round trip of every route in parallel, for all the texts at once.
+// The first failing route cancels the others.
+func (t T2) translateAll(ctx context.Context, texts []string) ([]Result, error) {
+	if len(t.config.Routes) == 0 {
+		return nil, errors.New("no pivot language")
 	}
-	if !t.config.DiffOnly {
-		fmt.Printf("# Pivot text (%s -> %s by %s)\n", t.config.SourceLang, t.config.PivotLang, t.backend.Name())
-		fmt.Println(firstPass.Text)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	routes := make([][]RouteResult, len(t.config.Routes))
+	errs := make([]error, len(t.config.Routes))
+	var wg sync.WaitGroup
+	for i, route := range t.config.Routes {
+		wg.Add(1)
+		go func(i int, route Route) {
+			defer wg.Done()
+			routes[i], errs[i] = t.roundTrips(ctx, texts, route)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, route)
+	}
+	wg.Wait()
+	if i, err := firstError(errs); err != nil {
+		return nil, fmt.Errorf("route %s: %w", t.config.Routes[i], err)
+	}
+
+	results := make([]Result, len(texts))
+	for i, text := range texts {
+		results[i] = Result{
+			Original:   text,
+			SourceLang: t.config.SourceLang,
+			Routes:     make([]RouteResult, len(routes)),
+		}
+		for j := range routes {
+			results[i].Routes[j] = routes[j][i]
+		}
 	}
+	return results, nil
+}
 
-	secondPass, err := t.backend.Translate(firstPass.Text, t.config.PivotLang, t.config.SourceLang)
+// roundTrip translates the text hop by hop along the route, back to the source language.
+func (t T2) roundTrip(ctx context.Context, text string, route Route) (RouteResult, error) {
+	results, err := t.roundTrips(ctx, []string{text}, route)
 	if err != nil {
-		return err
+		return RouteResult{}, err
+	}
+	return results[0], nil
+}
+
+// roundTrips translates the texts hop by hop along the route, back to the source language.
+// Each hop sends all the texts in a single batch to the backend.
+func (t T2) roundTrips(ctx context.Context, texts []string, route Route) ([]RouteResult, error) {
+	results := make([]RouteResult, len(texts))
+	for i := range results {
+		results[i].Route = route
 	}
-	if !t.config.DiffOnly {
-		fmt.Printf("# Double translated text (%s -> %s by %s)\n", t.config.PivotLang, t.config.SourceLang, t.backend.Name())
-		fmt.Println(secondPass.Text)
+	start := time.Now()
+
+	current := texts
+	hops := route.Hops(t.config.SourceLang)
+	for i, hop := range hops {
+		b := t.forward
+		if i == len(hops)-1 {
+			b = t.back
+		}
+		hopStart := time.Now()
+		translated, err := t.translateHop(ctx, b, current, hop)
+		if err != nil {
+			return nil, err
+		}
+		current = translated
+		duration := time.Since(hopStart)
+		for j := range results {
+			results[j].Hops = append(results[j].Hops, HopResult{
+				Source:   hop.Source,
+				Target:   hop.Target,
+				Backend:  b.Name(),
+				Text:     current[j],
+				Duration: duration,
+			})
+		}
 	}
 
-	if !t.config.DiffOnly {
-		fmt.Println("# Diff version")
+	duration := time.Since(start)
+	for i := range results {
+		results[i].Diff = t.diff.Compute(texts[i], current[i])
+		results[i].Distance = Distance(results[i].Diff)
+		results[i].Duration = duration
 	}
-	prettyPrint := t.diff.Print(text, secondPass.Text)
-	fmt.Println(prettyPrint)
+	return results, nil
+}
 
-	if t.config.CopyToClipboard {
-		if err := t.clipboard.Write(secondPass.Text); err != nil {
-			return err
+// translateHop translates the texts with the backend, keeping the protected spans untouched.
+func (t T2) translateHop(ctx context.Context, b Backend, texts []string, hop Hop) ([]string, error) {
+	masked := texts
+	spans := make([][]string, len(texts))
+	if t.protector != nil {
+		masked = make([]string, len(texts))
+		for i, text := range texts {
+			masked[i], spans[i] = t.protector.Mask(text)
 		}
 	}
 
-	return nil
+	passes, err := b.TranslateBatch(ctx, masked, hop.Source, hop.Target)
+	if err != nil {
+		return nil, err
+	}
+	if len(passes) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d translations for %d texts", b.Name(), len(passes), len(texts))
+	}
+
+	translated := make([]string, len(passes))
+	for i, pass := range passes {
+		translated[i] = pass.Text
+		if t.protector != nil {
+			translated[i], err = t.protector.Unmask(pass.Text, spans[i])
+			if err != nil {
+				return nil, fmt.Errorf("%s -> %s by %s: %w", hop.Source, hop.Target, b.Name(), err)
+			}
+		}
+	}
+	return translated, nil
+}
+
+// firstError returns the index and the first error that is not a cancellation,
+// or the first cancellation if there is nothing else.
+// Cancellations are the consequences of the failure of a sibling request.
+func firstError(errs []error) (int, error) {
+	first := -1
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return i, err
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return 0, nil
+	}
+	return first, errs[first]
 }
 
-// SelectBackend returns the translation service implementation to use.
-func SelectBackend(backend, endPoint, apiKey string) (Backend, error) {
-	if endPoint == "" || apiKey == "" {
-		return nil, errors.New("missing or incomplete configuration file (.t2.yaml)")
-	}
-
-	switch backend {
-	case "deepl":
-		return deepl.TranslationService{
-			Endpoint: endPoint,
-			ApiKey:   apiKey,
-		}, nil
-	case "google":
-		return google.TranslationService{
-			Endpoint: endPoint,
-			ApiKey:   apiKey,
-		}, nil
-	default:
+// SelectBackend returns the registered translation service implementation to use.
+// The HTTP client of the settings is shared by the backends to reuse the connections.
+func SelectBackend(name string, settings backend.Settings) (Backend, error) {
+	r, ok := backend.Lookup(name)
+	if !ok {
 		return nil, errors.New("unknown translation service")
 	}
+	return r.Create(settings)
 }
@@ -0,0 +1,146 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package t2_test
+
+import (
+	"context"
+	"errors"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/backend/mock"
+	"github.com/rangzen/t2/pkg/godiff"
+	"github.com/rangzen/t2/pkg/t2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTagger returns a mock appending the target language to the text at each hop.
+func newTagger(t *testing.T) mock.TranslationService {
+	t.Helper()
+	f := mock.Fixture{}
+	for _, lang := range []string{"FR", "DE", "EN-US"} {
+		f.Rules = append(f.Rules, mock.Rule{Target: lang, Pattern: `$`, Replace: "/" + lang})
+	}
+	return newMock(t, f)
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name    string
+		routes  []t2.Route
+		forward string
+		back    string
+		// want are the texts of the hops and their backends, for each route.
+		want     [][]string
+		backends [][]string
+	}{
+		{
+			name:     "single pivot",
+			routes:   []t2.Route{{"FR"}},
+			forward:  "A",
+			back:     "A",
+			want:     [][]string{{"hi/FR", "hi/FR/EN-US"}},
+			backends: [][]string{{"A", "A"}},
+		},
+		{
+			name:    "several pivots",
+			routes:  []t2.Route{{"FR"}, {"DE"}},
+			forward: "A",
+			back:    "A",
+			want: [][]string{
+				{"hi/FR", "hi/FR/EN-US"},
+				{"hi/DE", "hi/DE/EN-US"},
+			},
+			backends: [][]string{{"A", "A"}, {"A", "A"}},
+		},
+		{
+			name:     "chained route",
+			routes:   []t2.Route{{"FR", "DE"}},
+			forward:  "A",
+			back:     "A",
+			want:     [][]string{{"hi/FR", "hi/FR/DE", "hi/FR/DE/EN-US"}},
+			backends: [][]string{{"A", "A", "A"}},
+		},
+		{
+			name:     "forward and back",
+			routes:   []t2.Route{{"FR", "DE"}},
+			forward:  "A",
+			back:     "B",
+			want:     [][]string{{"hi/FR", "hi/FR/DE", "hi/FR/DE/EN-US"}},
+			backends: [][]string{{"A", "A", "B"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTagger(t)
+			config := t2.Config{SourceLang: "EN-US", Routes: tt.routes}
+			svc := t2.NewT2(config, named(m, tt.forward), named(m, tt.back), godiff.Diff{}, nil)
+
+			result, err := svc.Translate(context.Background(), "hi")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Routes) != len(tt.want) {
+				t.Fatalf("got %d routes, want %d", len(result.Routes), len(tt.want))
+			}
+			for i, rr := range result.Routes {
+				var texts, backends []string
+				for _, hop := range rr.Hops {
+					texts = append(texts, hop.Text)
+					backends = append(backends, hop.Backend)
+				}
+				if !reflect.DeepEqual(texts, tt.want[i]) {
+					t.Errorf("route %s: got texts %q, want %q", rr.Route, texts, tt.want[i])
+				}
+				if !reflect.DeepEqual(backends, tt.backends[i]) {
+					t.Errorf("route %s: got backends %q, want %q", rr.Route, backends, tt.backends[i])
+				}
+			}
+		})
+	}
+}
+
+var errFailing = errors.New("failing backend")
+
+// blocking waits for the cancellation of its context, except towards
+// the failing language where it fails at once.
+type blocking struct {
+	mock.TranslationService
+	failing string
+}
+
+func (b blocking) TranslateBatch(ctx context.Context, texts []string, source string, target string) ([]backend.TranslationResponse, error) {
+	if target == b.failing {
+		return nil, errFailing
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTranslateCancelsSiblings(t *testing.T) {
+	b := blocking{TranslationService: newMock(t, mock.Fixture{}), failing: "DE"}
+	config := t2.Config{SourceLang: "EN-US", Routes: []t2.Route{{"FR"}, {"DE"}, {"IT"}}}
+	svc := t2.NewT2(config, b, b, godiff.Diff{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := svc.Translate(ctx, "hi")
+	if !errors.Is(err, errFailing) {
+		t.Fatalf("got %v, want the error of the failing route", err)
+	}
+}
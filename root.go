@@ -18,15 +18,27 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/rangzen/t2/pkg/atotto"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/rangzen/t2/pkg/cache"
 	"github.com/rangzen/t2/pkg/godiff"
+	"github.com/rangzen/t2/pkg/mask"
+	"github.com/rangzen/t2/pkg/render"
+	"github.com/rangzen/t2/pkg/retry"
 	"github.com/rangzen/t2/pkg/t2"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Default values
@@ -36,60 +48,289 @@ var defaultClipboard = atotto.Clipboard{}
 // Cobra variables
 var cfgFile string
 var translationService string
+var forwardService string
+var backService string
 var sourceLang string
-var pivotLang string
+var pivotLangs []string
+var route []string
 var diffOnly bool
 var copyToClipboard bool
+var outputFormat string
+var timeout time.Duration
+var noCache bool
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:     "t2 [flags] \"Text to translate.\"",
-	Example: "t2 --pivot FR \"I will treat my wound.\"",
-	Short:   "Double translation",
+	Use: "t2 [flags] \"Text to translate.\"",
+	Example: `t2 --pivot FR "I will treat my wound."
+t2 --pivot FR,DE,ES,JA "I will treat my wound."
+t2 --route FR,DE "I will treat my wound."`,
+	Short: "Double translation",
 	Long: `Use online translation services to translate from
 a source language to a pivot language, then translate back
 to the source language.
 During this process, the most obvious errors are corrected.`,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := translate(args[0]); err != nil {
-			log.Fatal(err)
-		}
+		exitOnError(translate(args[0]))
 	},
 }
 
 func translate(t string) error {
-	ts, err := selectBackend()
+	ctx, cancel := newContext()
+	defer cancel()
+
+	forward, err := selectBackend(serviceOrDefault(forwardService))
+	if err != nil {
+		return err
+	}
+	back, err := selectBackend(serviceOrDefault(backService))
 	if err != nil {
 		return err
 	}
 
 	c := t2.Config{
 		SourceLang:      sourceLang,
-		PivotLang:       pivotLang,
-		DiffOnly:        diffOnly,
+		Routes:          routes(),
 		CopyToClipboard: copyToClipboard,
 	}
 
-	svc := t2.NewT2(c, ts, defaultDiff, defaultClipboard)
-	return svc.Translate(t)
+	svc, err := newT2(c, forward, back)
+	if err != nil {
+		return err
+	}
+	res, err := svc.Translate(ctx, t)
+	if err != nil {
+		return err
+	}
+	r, err := renderer()
+	if err != nil {
+		return err
+	}
+	return r.Translation(os.Stdout, res)
+}
+
+// newT2 returns the service protecting the terms and the patterns of the Protection section
+// of the configuration file, and the extra patterns, from the translation.
+func newT2(c t2.Config, forward, back t2.Backend, patterns ...string) (t2.T2, error) {
+	svc := t2.NewT2(c, forward, back, defaultDiff, defaultClipboard)
+	for _, term := range viper.GetStringSlice("Protection.Terms") {
+		if term != "" {
+			patterns = append(patterns, mask.Term(term))
+		}
+	}
+	patterns = append(patterns, viper.GetStringSlice("Protection.Patterns")...)
+	if len(patterns) == 0 {
+		return svc, nil
+	}
+	m, err := mask.New(patterns...)
+	if err != nil {
+		return t2.T2{}, fmt.Errorf("Protection: %w", err)
+	}
+	return svc.WithProtector(m), nil
+}
+
+// newContext returns the context of a command,
+// cancelled on Ctrl-C or after the --timeout delay.
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// renderer returns the renderer selected by the --output flag.
+func renderer() (render.Renderer, error) {
+	return render.New(outputFormat, defaultDiff, diffOnly)
 }
 
-func selectBackend() (t2.Backend, error) {
-	switch translationService {
-	case "deepl":
-		return t2.SelectBackend(translationService,
-			viper.GetString("TranslationServices.DeepL.Endpoint"),
-			viper.GetString("TranslationServices.DeepL.ApiKey"),
-		)
-	case "google":
-		return t2.SelectBackend(translationService,
-			viper.GetString("TranslationServices.Google.Endpoint"),
-			viper.GetString("TranslationServices.Google.ApiKey"),
-		)
-	default:
+// routes returns the chained route if any, otherwise one route per pivot language.
+func routes() []t2.Route {
+	if len(route) > 0 {
+		return []t2.Route{route}
+	}
+	return t2.PivotRoutes(pivotLangs)
+}
+
+// serviceOrDefault returns the given translation service,
+// or the one of the --translation-service flag if empty.
+func serviceOrDefault(service string) string {
+	if service == "" {
+		return translationService
+	}
+	return service
+}
+
+// configuredServices returns the registered translation services with a section
+// in the configuration file, and all their required options.
+func configuredServices() []string {
+	var services []string
+	for _, r := range backend.Registrations() {
+		if isConfigured(r) {
+			services = append(services, r.Name)
+		}
+	}
+	var typed []string
+	for name := range viper.GetStringMap("TranslationServices") {
+		if _, ok := backend.Lookup(name); ok {
+			continue
+		}
+		if r, ok := lookupService(name); ok && isConfigured(r) {
+			typed = append(typed, name)
+		}
+	}
+	sort.Strings(typed)
+	return append(services, typed...)
+}
+
+// isConfigured reports whether the translation service has a section
+// in the configuration file with all its required options.
+func isConfigured(r backend.Registration) bool {
+	if !viper.IsSet("TranslationServices." + r.ConfigKey) {
+		return false
+	}
+	settings := serviceSettings(r)
+	for _, o := range r.Options {
+		if o.Required && settings.Get(o.Name) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupService returns the registration of the translation service.
+// A section of the configuration file with a Type key declares another instance
+// of a registered service, like a second plugin, named after the section.
+// The configuration keys are case-insensitive, so the section is named in lower case
+// whatever the spelling.
+func lookupService(name string) (backend.Registration, bool) {
+	if r, ok := backend.Lookup(name); ok {
+		return r, true
+	}
+	section := strings.ToLower(name)
+	r, ok := backend.Lookup(viper.GetString("TranslationServices." + section + ".Type"))
+	if !ok {
+		return backend.Registration{}, false
+	}
+	r.Name = section
+	r.ConfigKey = section
+	return r, true
+}
+
+// serviceSettings returns the settings of the translation service from its section
+// in the configuration file.
+// Options set by flags override the ones of the file.
+func serviceSettings(r backend.Registration) backend.Settings {
+	key := "TranslationServices." + r.ConfigKey
+	values := viper.GetStringMapString(key)
+	for _, o := range r.Options {
+		if viper.IsSet(key + "." + o.Name) {
+			values[strings.ToLower(o.Name)] = viper.GetString(key + "." + o.Name)
+		}
+	}
+	settings := backend.Settings{Values: values}
+	if file := viper.ConfigFileUsed(); file != "" {
+		settings.Dir = filepath.Dir(file)
+	}
+	return settings
+}
+
+// selectBackend returns the translation service with retries and cache.
+func selectBackend(service string) (t2.Backend, error) {
+	r, ok := lookupService(service)
+	if !ok {
 		return nil, errors.New("unknown translation service")
 	}
+	settings := serviceSettings(r)
+	if _, ok := backend.Lookup(service); !ok {
+		settings.Name = r.Name
+	}
+	if !r.Offline {
+		client, err := httpClient()
+		if err != nil {
+			return nil, err
+		}
+		settings.Client = client
+	}
+
+	b, err := r.Create(settings)
+	if err != nil {
+		return nil, err
+	}
+	if r.Offline {
+		return b, nil
+	}
+	b = retry.New(b, retryPolicy())
+	if noCache || !viper.GetBool("Cache.Enabled") {
+		return b, nil
+	}
+	store, err := cacheStore()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(b, store), nil
+}
+
+// cacheStore returns the translation cache configured by the Cache section of the configuration file.
+func cacheStore() (cache.Store, error) {
+	dir := viper.GetString("Cache.Dir")
+	if dir == "" {
+		var err error
+		dir, err = cache.DefaultDir()
+		if err != nil {
+			return cache.Store{}, err
+		}
+	}
+	return cache.Store{
+		Dir:     dir,
+		TTL:     viper.GetDuration("Cache.TTL"),
+		MaxSize: int64(viper.GetSizeInBytes("Cache.MaxSize")),
+	}, nil
+}
+
+// sharedClient is the HTTP client of every backend, created on first use.
+var sharedClient *http.Client
+
+// httpClient returns the HTTP client configured by the Transport section of the configuration file.
+func httpClient() (*http.Client, error) {
+	if sharedClient != nil {
+		return sharedClient, nil
+	}
+	client, err := backend.NewHTTPClient(backend.TransportConfig{
+		Proxy:               viper.GetString("Transport.Proxy"),
+		CABundle:            viper.GetString("Transport.CABundle"),
+		ClientCertificate:   viper.GetString("Transport.ClientCertificate"),
+		ClientKey:           viper.GetString("Transport.ClientKey"),
+		Timeout:             viper.GetDuration("Transport.Timeout"),
+		MaxIdleConnsPerHost: viper.GetInt("Transport.MaxIdleConnsPerHost"),
+		IdleConnTimeout:     viper.GetDuration("Transport.IdleConnTimeout"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sharedClient = client
+	return sharedClient, nil
+}
+
+// retryPolicy returns the retry policy of the configuration file,
+// with the default values for the missing keys.
+func retryPolicy() retry.Policy {
+	p := retry.DefaultPolicy
+	if viper.IsSet("Retry.MaxAttempts") {
+		p.MaxAttempts = viper.GetInt("Retry.MaxAttempts")
+	}
+	if viper.IsSet("Retry.InitialDelay") {
+		p.InitialDelay = viper.GetDuration("Retry.InitialDelay")
+	}
+	if viper.IsSet("Retry.MaxDelay") {
+		p.MaxDelay = viper.GetDuration("Retry.MaxDelay")
+	}
+	return p
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -103,15 +344,40 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.t2.yaml)")
 	rootCmd.PersistentFlags().BoolVarP(&diffOnly, "diff-only", "d", false, "show only differences")
-	rootCmd.PersistentFlags().StringVarP(&translationService, "translation-service", "t", "deepl", "translation service to use (deepl or google)")
+	rootCmd.PersistentFlags().StringVarP(&translationService, "translation-service", "t", "deepl", "translation service to use ("+strings.Join(backend.Names(), ", ")+")")
+	rootCmd.PersistentFlags().StringVar(&forwardService, "forward", "", "translation service to the pivot languages (default is --translation-service)")
+	rootCmd.PersistentFlags().StringVar(&backService, "back", "", "translation service back to the source language (default is --translation-service)")
 	rootCmd.PersistentFlags().BoolVarP(&copyToClipboard, "to-clipboard", "c", false, "copy result to clipboard")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "maximum duration of the command, e.g. 30s (default is no timeout)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "do not use the translation cache")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "output format ("+strings.Join(render.Formats, ", ")+")")
+
+	rootCmd.PersistentFlags().StringSliceVarP(&pivotLangs, "pivot", "p", []string{"FR"}, "pivot language, or comma separated pivot languages to compare")
+	rootCmd.PersistentFlags().StringSliceVarP(&route, "route", "r", nil, "comma separated chain of pivot languages to walk before going back to the source language")
+	rootCmd.MarkFlagsMutuallyExclusive("pivot", "route")
+	rootCmd.PersistentFlags().StringVarP(&sourceLang, "source", "s", "EN-US", "source language")
 
-	rootCmd.Flags().StringVarP(&pivotLang, "pivot", "p", "FR", "pivot language")
-	rootCmd.Flags().StringVarP(&sourceLang, "source", "s", "EN-US", "source language")
+	// DeepL options, overriding the ones of the configuration file.
+	deeplFlags := []struct{ name, option, usage string }{
+		{"formality", "Formality", "DeepL formality (default, more, less, prefer_more, prefer_less)"},
+		{"glossary-id", "GlossaryID", "DeepL glossary to use"},
+		{"split-sentences", "SplitSentences", "DeepL sentence splitting (0, 1, nonewlines)"},
+		{"preserve-formatting", "PreserveFormatting", "DeepL formatting preservation (true, false)"},
+		{"tag-handling", "TagHandling", "DeepL tag handling (xml, html)"},
+		{"ignore-tags", "IgnoreTags", "DeepL comma separated tags whose content is not translated"},
+	}
+	for _, f := range deeplFlags {
+		rootCmd.PersistentFlags().String(f.name, "", f.usage)
+		cobra.CheckErr(viper.BindPFlag("TranslationServices.DeepL."+f.option, rootCmd.PersistentFlags().Lookup(f.name)))
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	viper.SetDefault("Cache.Enabled", true)
+	viper.SetDefault("Cache.TTL", "720h")
+	viper.SetDefault("Cache.MaxSize", "100MB")
+
 	if cfgFile != "" {
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
@@ -129,7 +395,7 @@ func initConfig() {
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil && !diffOnly {
+	if err := viper.ReadInConfig(); err == nil && !diffOnly && outputFormat == "text" {
 		_, errPrint := fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 		if errPrint != nil {
 			log.Fatal(errPrint)
@@ -0,0 +1,68 @@
+/*
+Copyright © 2021 Cedric L'homme <public@l-homme.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"github.com/rangzen/t2/pkg/backend"
+	"github.com/spf13/cobra"
+)
+
+// servicesCmd represents the services command
+var servicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "List the available translation services",
+	Long: `List the available translation services, with their options
+in the TranslationServices section of the configuration file.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printServices()
+	},
+}
+
+func printServices() {
+	configured := map[string]bool{}
+	for _, name := range configuredServices() {
+		configured[name] = true
+	}
+	for _, r := range backend.Registrations() {
+		status := ""
+		if configured[r.Name] {
+			status = " (configured)"
+		}
+		fmt.Printf("%s%s: %s\n", r.Name, status, r.Description)
+		for _, o := range r.Options {
+			required := ""
+			if o.Required {
+				required = ", required"
+			}
+			fmt.Printf("  TranslationServices.%s.%s: %s%s\n", r.ConfigKey, o.Name, o.Description, required)
+		}
+	}
+	for _, name := range configuredServices() {
+		if _, ok := backend.Lookup(name); ok {
+			continue
+		}
+		r, _ := lookupService(name)
+		fmt.Printf("%s (configured): %s\n", name, r.Description)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(servicesCmd)
+}
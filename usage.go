@@ -18,9 +18,8 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package main
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
-	"log"
+	"os"
 )
 
 // usageCmd represents the usage command
@@ -30,24 +29,28 @@ var usageCmd = &cobra.Command{
 	Long: `Display usage and limit of the selected translation service
 if the service provide such informations.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := printUsage(); err != nil {
-			log.Fatal(err)
-		}
+		exitOnError(printUsage())
 	},
 }
 
 func printUsage() error {
-	ts, err := selectBackend()
+	ctx, cancel := newContext()
+	defer cancel()
+
+	ts, err := selectBackend(translationService)
 	if err != nil {
 		return err
 	}
 
-	u, err := ts.Usage()
+	u, err := ts.Usage(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	r, err := renderer()
+	if err != nil {
+		return err
 	}
-	fmt.Printf("Usage: %d/%d\n", u.Used, u.Limit)
-	return nil
+	return r.Usage(os.Stdout, ts.Name(), u)
 }
 
 func init() {